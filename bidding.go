@@ -0,0 +1,590 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	auctionModeOpen              = "open"
+	auctionModeSealedFirstPrice  = "sealedFirstPrice"
+	auctionModeSealedSecondPrice = "sealedSecondPrice"
+)
+
+const (
+	pricingPayAsBid = "payAsBid"
+	pricingUniform  = "uniform"
+)
+
+// isSealedMode reports whether bids of an auction stay hidden until it is closed.
+func isSealedMode(mode string) bool {
+	return mode == auctionModeSealedFirstPrice || mode == auctionModeSealedSecondPrice
+}
+
+const (
+	bidIncrementFixed      = "fixed"
+	bidIncrementPercentage = "percentage"
+	bidIncrementTable      = "table"
+)
+
+// bidIncrementRule returns the increment rule of an auction, falling back to the global settings.
+func bidIncrementRule(settings *Settings, auction *core.Record) (BidIncrementRule, error) {
+	if incrementType := auction.GetString("bidIncrementType"); incrementType != "" {
+		table, err := parseIncrementTable(auction.GetString("bidIncrementTable"))
+		if err != nil {
+			return BidIncrementRule{}, err
+		}
+		return BidIncrementRule{Type: incrementType, Value: auction.GetInt("bidIncrementValue"), Table: table}, nil
+	}
+	table, err := parseIncrementTable(string(settings.BidIncrementTable))
+	if err != nil {
+		return BidIncrementRule{}, err
+	}
+	return BidIncrementRule{Type: settings.BidIncrementType, Value: settings.BidIncrementValue, Table: table}, nil
+}
+
+// parseIncrementTable decodes a tiered increment table, an empty value meaning no tiers.
+func parseIncrementTable(raw string) ([]BidIncrementTier, error) {
+	if raw == "" || raw == "null" {
+		return nil, nil
+	}
+	table := []BidIncrementTier{}
+	if err := json.Unmarshal([]byte(raw), &table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// minimumIncrement returns the smallest step a bid must raise the current bid by.
+// Tiers of a table apply below their bound, a tier without a bound applies to everything above.
+// Tiers are matched from the lowest bound up, whatever order they were entered in.
+func minimumIncrement(rule BidIncrementRule, currentBid int) int {
+	step := 1
+	switch rule.Type {
+	case bidIncrementFixed:
+		step = rule.Value
+	case bidIncrementPercentage:
+		step = int(math.Ceil(float64(currentBid) * float64(rule.Value) / 100))
+	case bidIncrementTable:
+		tiers := slices.Clone(rule.Table)
+		slices.SortStableFunc(tiers, func(a, b BidIncrementTier) int {
+			if a.Below == 0 || b.Below == 0 {
+				return cmp.Compare(b.Below, a.Below)
+			}
+			return cmp.Compare(a.Below, b.Below)
+		})
+		for _, tier := range tiers {
+			step = tier.Step
+			if tier.Below == 0 || currentBid < tier.Below {
+				break
+			}
+		}
+	}
+	return max(step, 1)
+}
+
+// openingBid returns the lowest amount accepted as the first bid on an auction.
+func openingBid(startingBid int) int {
+	return max(startingBid, 1)
+}
+
+// minimumBid returns the lowest amount accepted as the next bid on an auction.
+func minimumBid(rule BidIncrementRule, startingBid int, currentBid int) int {
+	if currentBid == 0 {
+		return openingBid(startingBid)
+	}
+	return max(startingBid, currentBid+minimumIncrement(rule, currentBid))
+}
+
+// multiUnitMinimumBid returns the lowest bid that takes a unit of a multi-quantity auction.
+// A user who already holds a bid has to raise it.
+func multiUnitMinimumBid(rule BidIncrementRule, startingBid int, ranked []rankedBid, quantity int, userId string) int {
+	minBid := openingBid(startingBid)
+	if len(ranked) >= quantity {
+		lowest := ranked[quantity-1].Amount
+		minBid = max(minBid, lowest+minimumIncrement(rule, lowest))
+	}
+	for _, bid := range ranked {
+		if bid.UserId == userId {
+			minBid = max(minBid, bid.Amount+1)
+		}
+	}
+	return minBid
+}
+
+// proxyContest describes a new bid challenging the current leader of an auction.
+type proxyContest struct {
+	LeaderId   string
+	CurrentBid int
+	LeaderMax  int
+	BidderId   string
+	Amount     int
+	BidderMax  int
+}
+
+// proxyStep is a bid placed automatically on behalf of a user.
+type proxyStep struct {
+	UserId string
+	Amount int
+}
+
+// proxyResult is the outcome of a proxy contest.
+type proxyResult struct {
+	WinnerId   string
+	CurrentBid int
+	Steps      []proxyStep
+}
+
+// resolveProxyBid raises the maximum bids of the leader and the challenger against each other
+// until one of them is exhausted. The earlier (leading) bid wins ties.
+func resolveProxyBid(c proxyContest, rule BidIncrementRule) proxyResult {
+	leaderMax := max(c.LeaderMax, c.CurrentBid)
+	bidderMax := max(c.BidderMax, c.Amount)
+	if c.LeaderId == "" {
+		return proxyResult{WinnerId: c.BidderId, CurrentBid: c.Amount}
+	}
+
+	if bidderMax > leaderMax {
+		price := max(c.Amount, min(bidderMax, leaderMax+minimumIncrement(rule, leaderMax)))
+		result := proxyResult{WinnerId: c.BidderId, CurrentBid: price}
+		if leaderMax > c.CurrentBid {
+			result.Steps = append(result.Steps, proxyStep{UserId: c.LeaderId, Amount: leaderMax})
+		}
+		if price > c.Amount {
+			result.Steps = append(result.Steps, proxyStep{UserId: c.BidderId, Amount: price})
+		}
+		return result
+	}
+
+	price := leaderMax
+	if bidderMax < leaderMax {
+		price = min(leaderMax, bidderMax+minimumIncrement(rule, bidderMax))
+	}
+	result := proxyResult{WinnerId: c.LeaderId, CurrentBid: price}
+	if bidderMax > c.Amount {
+		result.Steps = append(result.Steps, proxyStep{UserId: c.BidderId, Amount: bidderMax})
+	}
+	result.Steps = append(result.Steps, proxyStep{UserId: c.LeaderId, Amount: price})
+	return result
+}
+
+const (
+	bidSourceWeb   = "web"
+	bidSourceApi   = "api"
+	bidSourceProxy = "proxy"
+)
+
+// findBid returns the bid currently standing for a user on an auction, or nil when the user has not bid yet.
+// Bids are append-only, the latest one that was not retracted stands.
+func findBid(app core.App, auctionId string, userId string) (*core.Record, error) {
+	records, err := app.FindRecordsByFilter(
+		"bids",
+		"auction = {:auctionId} && user = {:userId} && retracted = false",
+		"-created,-amount",
+		1,
+		0,
+		dbx.Params{"auctionId": auctionId, "userId": userId},
+	)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+// saveBid appends a bid of a user on an auction to the bid history.
+// Automatic raises keep the maximum the user submitted and point to the bid that forced them.
+func saveBid(app core.App, auctionId string, userId string, amount int, maxAmount int, source string, triggerId string) (*core.Record, error) {
+	if source == bidSourceProxy {
+		previous, err := findBid(app, auctionId, userId)
+		if err != nil {
+			return nil, err
+		}
+		if previous != nil {
+			maxAmount = previous.GetInt("maxAmount")
+		}
+	}
+	collection, err := app.FindCachedCollectionByNameOrId("bids")
+	if err != nil {
+		return nil, err
+	}
+	record := core.NewRecord(collection)
+	record.Set("auction", auctionId)
+	record.Set("user", userId)
+	record.Set("amount", amount)
+	record.Set("maxAmount", maxAmount)
+	record.Set("source", source)
+	record.Set("triggeredBy", triggerId)
+	record.Set("timestamp", time.Now().Unix())
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// findBidHistory returns every bid placed on an auction in the order they were placed, retracted ones included.
+func findBidHistory(app core.App, auctionId string) ([]*core.Record, error) {
+	return app.FindRecordsByFilter("bids", "auction = {:auctionId}", "created,amount", 0, 0, dbx.Params{"auctionId": auctionId})
+}
+
+// rankedBid is a bid reduced to the values used to rank it.
+type rankedBid struct {
+	UserId    string
+	Amount    int
+	MaxAmount int
+	Timestamp int64
+}
+
+// rankBids orders bids from the highest amount down, earlier bids first on equal amounts.
+func rankBids(bids []rankedBid) {
+	sort.SliceStable(bids, func(i, j int) bool {
+		if bids[i].Amount != bids[j].Amount {
+			return bids[i].Amount > bids[j].Amount
+		}
+		return bids[i].Timestamp < bids[j].Timestamp
+	})
+}
+
+// sealedPrice returns what the winner of a sealed auction is charged for the ranked bids.
+// Second-price auctions charge the runner-up's bid plus one, never less than the opening bid
+// nor more than the winning bid.
+func sealedPrice(mode string, ranked []rankedBid, startingBid int) int {
+	if len(ranked) == 0 {
+		return 0
+	}
+	if mode != auctionModeSealedSecondPrice {
+		return ranked[0].Amount
+	}
+	if len(ranked) == 1 {
+		return min(ranked[0].Amount, openingBid(startingBid))
+	}
+	return min(ranked[0].Amount, max(openingBid(startingBid), ranked[1].Amount+1))
+}
+
+// findRankedBids loads the standing bid of every bidder on an auction ordered by rankBids.
+func findRankedBids(app core.App, auctionId string) ([]rankedBid, error) {
+	records, err := app.FindRecordsByFilter("bids", "auction = {:auctionId} && retracted = false", "created,amount", 0, 0, dbx.Params{"auctionId": auctionId})
+	if err != nil {
+		return nil, err
+	}
+	standing := map[string]*core.Record{}
+	userIds := []string{}
+	for _, record := range records {
+		if standing[record.GetString("user")] == nil {
+			userIds = append(userIds, record.GetString("user"))
+		}
+		standing[record.GetString("user")] = record
+	}
+	ranked := make([]rankedBid, 0, len(userIds))
+	for _, userId := range userIds {
+		record := standing[userId]
+		timestamp, _ := strconv.ParseInt(record.GetString("timestamp"), 10, 64)
+		ranked = append(ranked, rankedBid{
+			UserId:    record.GetString("user"),
+			Amount:    record.GetInt("amount"),
+			MaxAmount: record.GetInt("maxAmount"),
+			Timestamp: timestamp,
+		})
+	}
+	rankBids(ranked)
+	return ranked, nil
+}
+
+// findBidderIds returns every user who placed a bid on an auction.
+func findBidderIds(app core.App, auctionId string) ([]string, error) {
+	bidderIds := []string{}
+	err := app.DB().
+		Select("user").
+		Distinct(true).
+		From("bids").
+		Where(dbx.HashExp{"auction": auctionId}).
+		Column(&bidderIds)
+	return bidderIds, err
+}
+
+// findRetractableBids returns the latest bid a user placed on an auction together with the automatic raises made
+// on their behalf since, newest first. The bid the user placed is the last one.
+func findRetractableBids(app core.App, auctionId string, userId string) ([]*core.Record, error) {
+	records, err := app.FindRecordsByFilter(
+		"bids",
+		"auction = {:auctionId} && user = {:userId} && retracted = false",
+		"-created,-amount",
+		0,
+		0,
+		dbx.Params{"auctionId": auctionId, "userId": userId},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for i, record := range records {
+		if record.GetString("source") != bidSourceProxy {
+			return records[:i+1], nil
+		}
+	}
+	return nil, nil
+}
+
+// retractBid marks bids of a user on an ongoing auction as retracted, so their earlier bid stands again.
+// Automatic raises the retracted bids forced on other bidders are retracted with them.
+// Open auctions hand the lead back to the best standing bids, sealed ones only adjust the reservation.
+func retractBid(app core.App, settings *Settings, auction *core.Record, bids []*core.Record) error {
+	userId := ""
+	for _, bid := range bids {
+		userId = bid.GetString("user")
+		forced, err := app.FindRecordsByFilter(
+			"bids",
+			"auction = {:auctionId} && triggeredBy = {:bidId} && retracted = false",
+			"",
+			0,
+			0,
+			dbx.Params{"auctionId": auction.Id, "bidId": bid.Id},
+		)
+		if err != nil {
+			return err
+		}
+		for _, record := range append(forced, bid) {
+			record.Set("retracted", true)
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+	}
+	if !isSealedMode(auction.GetString("mode")) {
+		if err := setReservation(app, auction.Id, userId, 0); err != nil {
+			return err
+		}
+		return restoreLeaders(app, settings, auction)
+	}
+	standing, err := findBid(app, auction.Id, userId)
+	if err != nil {
+		return err
+	}
+	tokensToReserve := 0
+	if standing != nil {
+		tokensToReserve = standing.GetInt("amount")
+	}
+	return setReservation(app, auction.Id, userId, tokensToReserve)
+}
+
+// restoreLeaders recomputes the leaders of an open auction from its standing bids and moves the reservations to them.
+// Bidders who can no longer cover their bid are passed over. Single unit auctions settle the price
+// with a proxy contest between the two best remaining bids.
+func restoreLeaders(app core.App, settings *Settings, auction *core.Record) error {
+	ranked, err := findRankedBids(app, auction.Id)
+	if err != nil {
+		return err
+	}
+	quantity := max(auction.GetInt("quantity"), 1)
+	eligible := []rankedBid{}
+	for _, bid := range ranked {
+		if len(eligible) > quantity {
+			break
+		}
+		user, err := app.FindRecordById("users", bid.UserId)
+		if err != nil {
+			return err
+		}
+		available, err := availableTokens(app, user, auction.Id)
+		if err != nil {
+			return err
+		}
+		if bid.Amount <= available {
+			bid.MaxAmount = max(bid.Amount, min(bid.MaxAmount, available))
+			eligible = append(eligible, bid)
+		}
+	}
+
+	winners := eligible[:min(quantity, len(eligible))]
+	winnerIds := []string{}
+	currentBid := 0
+	for _, bid := range winners {
+		winnerIds = append(winnerIds, bid.UserId)
+		currentBid = bid.Amount
+	}
+	if quantity == 1 && len(eligible) > 1 {
+		rule, err := bidIncrementRule(settings, auction)
+		if err != nil {
+			return err
+		}
+		leader, challenger := eligible[0], eligible[1]
+		result := resolveProxyBid(proxyContest{
+			LeaderId:   leader.UserId,
+			CurrentBid: leader.Amount,
+			LeaderMax:  leader.MaxAmount,
+			BidderId:   challenger.UserId,
+			Amount:     challenger.Amount,
+			BidderMax:  challenger.MaxAmount,
+		}, rule)
+		if result.WinnerId != leader.UserId || result.CurrentBid > leader.Amount {
+			for _, step := range result.Steps {
+				if _, err := saveBid(app, auction.Id, step.UserId, step.Amount, 0, bidSourceProxy, ""); err != nil {
+					return err
+				}
+			}
+			winnerIds = []string{result.WinnerId}
+			currentBid = result.CurrentBid
+		}
+	}
+
+	reserved := map[string]int{}
+	for _, bid := range eligible {
+		if !slices.Contains(winnerIds, bid.UserId) {
+			continue
+		}
+		reserved[bid.UserId] = bid.Amount
+		if quantity == 1 {
+			reserved[bid.UserId] = currentBid
+			if settings.ProxyReserveMaximum {
+				reserved[bid.UserId] = max(currentBid, bid.MaxAmount)
+			}
+		}
+	}
+	for _, bid := range ranked {
+		if err := setReservation(app, auction.Id, bid.UserId, reserved[bid.UserId]); err != nil {
+			return err
+		}
+	}
+
+	auction.Set("currentBid", currentBid)
+	if quantity > 1 {
+		auction.Set("winners", winnerIds)
+	} else if len(winnerIds) > 0 {
+		auction.Set("winner", winnerIds[0])
+	} else {
+		auction.Set("winner", "")
+	}
+	return app.Save(auction)
+}
+
+// auctionAward is a unit of an auction won by a user at a price.
+type auctionAward struct {
+	UserId string
+	Amount int
+}
+
+// unitAwards returns the ranked bids winning the units of an auction with the price each winner pays.
+// Uniform pricing charges every winner the lowest winning bid.
+func unitAwards(pricing string, ranked []rankedBid, quantity int) []auctionAward {
+	winners := ranked[:min(quantity, len(ranked))]
+	awards := make([]auctionAward, 0, len(winners))
+	for _, bid := range winners {
+		price := bid.Amount
+		if pricing == pricingUniform {
+			price = winners[len(winners)-1].Amount
+		}
+		awards = append(awards, auctionAward{UserId: bid.UserId, Amount: price})
+	}
+	return awards
+}
+
+// resolveAwards determines who won an auction and at what price, revealing sealed bids
+// and storing the outcome on the auction record.
+func resolveAwards(app core.App, auction *core.Record) ([]auctionAward, error) {
+	quantity := auction.GetInt("quantity")
+	mode := auction.GetString("mode")
+	if quantity <= 1 && !isSealedMode(mode) {
+		if auction.GetString("winner") == "" {
+			return nil, nil
+		}
+		return []auctionAward{{UserId: auction.GetString("winner"), Amount: auction.GetInt("currentBid")}}, nil
+	}
+
+	ranked, err := findRankedBids(app, auction.Id)
+	if err != nil || len(ranked) == 0 {
+		return nil, err
+	}
+	if quantity <= 1 {
+		price := sealedPrice(mode, ranked, auction.GetInt("startingBid"))
+		auction.Set("winner", ranked[0].UserId)
+		auction.Set("currentBid", price)
+		return []auctionAward{{UserId: ranked[0].UserId, Amount: price}}, nil
+	}
+
+	awards := unitAwards(auction.GetString("pricing"), ranked, quantity)
+	winnerIds := make([]string, 0, len(awards))
+	for _, award := range awards {
+		winnerIds = append(winnerIds, award.UserId)
+	}
+	auction.Set("winners", winnerIds)
+	auction.Set("currentBid", awards[len(awards)-1].Amount)
+	return awards, nil
+}
+
+// applyReservePrice drops the awards priced below the reserve and updates the auction winners to match.
+func applyReservePrice(auction *core.Record, awards []auctionAward, reservePrice int) []auctionAward {
+	kept := make([]auctionAward, 0, len(awards))
+	for _, award := range awards {
+		if award.Amount >= reservePrice {
+			kept = append(kept, award)
+		}
+	}
+	if len(kept) == len(awards) || auction.GetInt("quantity") <= 1 {
+		return kept
+	}
+	winnerIds := make([]string, 0, len(kept))
+	for _, award := range kept {
+		winnerIds = append(winnerIds, award.UserId)
+	}
+	auction.Set("winners", winnerIds)
+	if len(kept) > 0 {
+		auction.Set("currentBid", kept[len(kept)-1].Amount)
+	}
+	return kept
+}
+
+// antiSnipeRule returns the floating end rule of an auction, falling back to the global settings.
+func antiSnipeRule(settings *Settings, auction *core.Record) AntiSnipeRule {
+	switch auction.GetString("antiSnipe") {
+	case "enabled":
+		extendMinutes := auction.GetInt("antiSnipeMinutes")
+		if extendMinutes <= 0 {
+			extendMinutes = settings.FloatingEndOfAuctionMinutes
+		}
+		return AntiSnipeRule{
+			Enabled:        true,
+			ExtendMinutes:  extendMinutes,
+			TriggerMinutes: auction.GetInt("antiSnipeTriggerMinutes"),
+			CapMinutes:     auction.GetInt("antiSnipeCapMinutes"),
+		}
+	case "disabled":
+		return AntiSnipeRule{}
+	}
+	return AntiSnipeRule{
+		Enabled:        settings.EnableFloatingEndOfAuction,
+		ExtendMinutes:  settings.FloatingEndOfAuctionMinutes,
+		TriggerMinutes: settings.FloatingEndTriggerMinutes,
+		CapMinutes:     settings.FloatingEndCapMinutes,
+	}
+}
+
+// applyAntiSnipe pushes the end of an auction to now plus the extension when a bid lands inside the trigger window.
+// A trigger of zero extends on every bid, a cap of zero allows unlimited extension.
+func applyAntiSnipe(rule AntiSnipeRule, auction *core.Record, now time.Time) {
+	if !rule.Enabled || rule.ExtendMinutes <= 0 {
+		return
+	}
+	endTime := auction.GetDateTime("endTime").Time()
+	if rule.TriggerMinutes > 0 && endTime.Sub(now) > time.Duration(rule.TriggerMinutes)*time.Minute {
+		return
+	}
+	extension := now.Add(time.Duration(rule.ExtendMinutes) * time.Minute).Sub(endTime)
+	if extension <= 0 {
+		return
+	}
+	extended := time.Duration(auction.GetInt("antiSnipeExtension")) * time.Second
+	if rule.CapMinutes > 0 {
+		extension = min(extension, time.Duration(rule.CapMinutes)*time.Minute-extended)
+		if extension <= 0 {
+			return
+		}
+	}
+	auction.Set("endTime", endTime.Add(extension))
+	auction.Set("antiSnipeExtension", int((extended + extension).Seconds()))
+}
@@ -0,0 +1,812 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// TestResolveProxyBid verifies automatic raises between the leader and a challenger.
+func TestResolveProxyBid(t *testing.T) {
+	cases := []struct {
+		name          string
+		contest       proxyContest
+		expectWinner  string
+		expectCurrent int
+		expectSteps   []proxyStep
+	}{
+		{
+			name:          "first bid",
+			contest:       proxyContest{BidderId: "b", Amount: 10, BidderMax: 50},
+			expectWinner:  "b",
+			expectCurrent: 10,
+		},
+		{
+			name:          "challenger exceeds leader maximum",
+			contest:       proxyContest{LeaderId: "a", CurrentBid: 10, LeaderMax: 30, BidderId: "b", Amount: 11, BidderMax: 50},
+			expectWinner:  "b",
+			expectCurrent: 31,
+			expectSteps:   []proxyStep{{UserId: "a", Amount: 30}, {UserId: "b", Amount: 31}},
+		},
+		{
+			name:          "leader maximum holds",
+			contest:       proxyContest{LeaderId: "a", CurrentBid: 10, LeaderMax: 30, BidderId: "b", Amount: 15, BidderMax: 20},
+			expectWinner:  "a",
+			expectCurrent: 21,
+			expectSteps:   []proxyStep{{UserId: "b", Amount: 20}, {UserId: "a", Amount: 21}},
+		},
+		{
+			name:          "tie goes to the earlier bid",
+			contest:       proxyContest{LeaderId: "a", CurrentBid: 10, LeaderMax: 30, BidderId: "b", Amount: 30, BidderMax: 30},
+			expectWinner:  "a",
+			expectCurrent: 30,
+			expectSteps:   []proxyStep{{UserId: "a", Amount: 30}},
+		},
+		{
+			name:          "leader without maximum is outbid",
+			contest:       proxyContest{LeaderId: "a", CurrentBid: 10, LeaderMax: 10, BidderId: "b", Amount: 12, BidderMax: 12},
+			expectWinner:  "b",
+			expectCurrent: 12,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := resolveProxyBid(tc.contest, BidIncrementRule{})
+			if result.WinnerId != tc.expectWinner || result.CurrentBid != tc.expectCurrent {
+				t.Fatalf("resolveProxyBid() = %s@%d, expected %s@%d", result.WinnerId, result.CurrentBid, tc.expectWinner, tc.expectCurrent)
+			}
+			if len(result.Steps) != len(tc.expectSteps) {
+				t.Fatalf("expected steps %v, got %v", tc.expectSteps, result.Steps)
+			}
+			for i, step := range tc.expectSteps {
+				if result.Steps[i] != step {
+					t.Fatalf("expected steps %v, got %v", tc.expectSteps, result.Steps)
+				}
+			}
+		})
+	}
+}
+
+// TestSetReservationTracksReservedTokens verifies reservations keep users.reservedTokens and the reservations view in sync.
+func TestSetReservationTracksReservedTokens(t *testing.T) {
+	app := newTestApp(t)
+
+	user := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	auction := createTestAuction(t, app, 10)
+
+	if err := setReservation(app.App, auction.Id, user.Id, 40); err != nil {
+		t.Fatalf("setReservation returned error: %v", err)
+	}
+	if err := setReservation(app.App, auction.Id, user.Id, 25); err != nil {
+		t.Fatalf("setReservation returned error: %v", err)
+	}
+	assertReservedTokens(t, app, user.Id, 25)
+	if record, err := findReservation(app.App, auction.Id, user.Id); err != nil || record == nil || record.GetInt("amount") != 25 {
+		t.Fatalf("expected the reservations view to show 25, got %v (%v)", record, err)
+	}
+
+	if err := releaseReservations(app.App, auction.Id); err != nil {
+		t.Fatalf("releaseReservations returned error: %v", err)
+	}
+	assertReservedTokens(t, app, user.Id, 0)
+
+	if record, err := findReservation(app.App, auction.Id, user.Id); err != nil || record != nil {
+		t.Fatalf("expected reservation to be removed, got %v (%v)", record, err)
+	}
+}
+
+// TestHandleBidRaisesProxyBid verifies a challenger is outbid by the leader's hidden maximum.
+func TestHandleBidRaisesProxyBid(t *testing.T) {
+	app := newTestApp(t)
+
+	leader := createTestUser(t, app, "leader@example.com", []string{"member"})
+	challenger := createTestUser(t, app, "challenger@example.com", []string{"member"})
+	setTestTokens(t, app, leader, 100)
+	setTestTokens(t, app, challenger, 100)
+	auction := createTestAuction(t, app, 10)
+
+	placeTestBid(t, app, leader, auction.Id, `{"amount":10,"maxAmount":50}`)
+	placeTestBid(t, app, challenger, auction.Id, `{"amount":30}`)
+
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("winner") != leader.Id || auction.GetInt("currentBid") != 31 {
+		t.Fatalf("expected leader to hold the auction at 31, got %s@%d", auction.GetString("winner"), auction.GetInt("currentBid"))
+	}
+	assertReservedTokens(t, app, leader.Id, 31)
+	assertReservedTokens(t, app, challenger.Id, 0)
+
+	leaderBid, err := findBid(app.App, auction.Id, leader.Id)
+	if err != nil || leaderBid == nil {
+		t.Fatalf("failed to find leader bid: %v", err)
+	}
+	if leaderBid.GetString("source") != bidSourceProxy || leaderBid.GetInt("amount") != 31 || leaderBid.GetInt("maxAmount") != 50 {
+		t.Fatalf("unexpected leader bid record: %v", leaderBid)
+	}
+}
+
+// TestGetBidHistory verifies every raise is kept in order with where it came from.
+func TestGetBidHistory(t *testing.T) {
+	app := newTestApp(t)
+
+	leader := createTestUser(t, app, "leader@example.com", []string{"member"})
+	challenger := createTestUser(t, app, "challenger@example.com", []string{"member"})
+	setTestTokens(t, app, leader, 100)
+	setTestTokens(t, app, challenger, 100)
+	auction := createTestAuction(t, app, 10)
+
+	placeTestBid(t, app, leader, auction.Id, `{"amount":10,"maxAmount":50}`)
+	e, _ := newTestRequestEvent(app, challenger, `{"amount":30}`, map[string]string{"id": auction.Id})
+	if err := bidApi(e); err != nil {
+		t.Fatalf("bidApi returned error: %v", err)
+	}
+
+	validateTestUser(t, app, challenger)
+	history := getTestBidHistory(t, app, challenger, auction.Id)
+	expected := []BidHistoryEntry{
+		{User: leader.Id, Amount: 10, Source: bidSourceWeb},
+		{User: challenger.Id, Amount: 30, Source: bidSourceApi},
+		{User: leader.Id, Amount: 31, Source: bidSourceProxy},
+	}
+	if len(history) != len(expected) {
+		t.Fatalf("expected %d bids in the history, got %d", len(expected), len(history))
+	}
+	for i, entry := range history {
+		if entry.User != expected[i].User || entry.Amount != expected[i].Amount || entry.Source != expected[i].Source {
+			t.Fatalf("unexpected bid %d: %+v", i, entry)
+		}
+	}
+
+	ranked, err := findRankedBids(app.App, auction.Id)
+	if err != nil {
+		t.Fatalf("findRankedBids returned error: %v", err)
+	}
+	if len(ranked) != 2 || ranked[0].UserId != leader.Id || ranked[0].Amount != 31 || ranked[0].MaxAmount != 50 {
+		t.Fatalf("expected the leader's latest bid to stand first, got %+v", ranked)
+	}
+}
+
+// TestGetBidHistoryHidesBidders verifies unvalidated users are refused, sealed bids stay hidden until the auction
+// has a result and anonymous bidders are shown without their id.
+func TestGetBidHistoryHidesBidders(t *testing.T) {
+	app := newTestApp(t)
+
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	member := createTestUser(t, app, "member@example.com", []string{"member"})
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	setTestTokens(t, app, bidder, 100)
+	auction := createTestAuction(t, app, 10)
+	auction.Set("mode", auctionModeSealedFirstPrice)
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+	placeTestBid(t, app, bidder, auction.Id, `{"amount":80}`)
+
+	e, _ := newTestRequestEvent(app, member, "", map[string]string{"id": auction.Id})
+	if err := getBidHistory(e); err == nil {
+		t.Fatalf("expected an unvalidated user to be refused")
+	}
+	validateTestUser(t, app, member)
+
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if err := pauseAuction(app, auction); err != nil {
+		t.Fatalf("pauseAuction returned error: %v", err)
+	}
+	if history := getTestBidHistory(t, app, member, auction.Id); len(history) != 0 {
+		t.Fatalf("expected sealed bids of a paused auction to stay hidden, got %+v", history)
+	}
+	if history := getTestBidHistory(t, app, manager, auction.Id); len(history) != 1 {
+		t.Fatalf("expected managers to see sealed bids, got %+v", history)
+	}
+
+	auction.Set("state", "finished")
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+	if history := getTestBidHistory(t, app, member, auction.Id); len(history) != 1 || history[0].User != bidder.Id {
+		t.Fatalf("expected sealed bids to be revealed once finished, got %+v", history)
+	}
+
+	insertSettingsRecord(t, app)
+	if _, err := app.DB().Update("settings", dbx.Params{"anonymousBidders": true}, nil).Execute(); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+	if history := getTestBidHistory(t, app, member, auction.Id); len(history) != 1 || history[0].User != "" || history[0].UserName != "" {
+		t.Fatalf("expected an anonymous bidder, got %+v", history)
+	}
+}
+
+// TestMinimumIncrement verifies fixed, percentage and tiered increment rules.
+func TestMinimumIncrement(t *testing.T) {
+	table := []BidIncrementTier{{Below: 100, Step: 5}, {Below: 500, Step: 10}, {Step: 25}}
+	unsorted := []BidIncrementTier{{Step: 25}, {Below: 500, Step: 10}, {Below: 100, Step: 5}}
+	cases := []struct {
+		name       string
+		rule       BidIncrementRule
+		currentBid int
+		expected   int
+	}{
+		{"default", BidIncrementRule{}, 250, 1},
+		{"fixed", BidIncrementRule{Type: bidIncrementFixed, Value: 10}, 250, 10},
+		{"fixed without value", BidIncrementRule{Type: bidIncrementFixed}, 250, 1},
+		{"percentage", BidIncrementRule{Type: bidIncrementPercentage, Value: 5}, 250, 13},
+		{"percentage of small bid", BidIncrementRule{Type: bidIncrementPercentage, Value: 5}, 10, 1},
+		{"table lower tier", BidIncrementRule{Type: bidIncrementTable, Table: table}, 99, 5},
+		{"table middle tier", BidIncrementRule{Type: bidIncrementTable, Table: table}, 100, 10},
+		{"table open tier", BidIncrementRule{Type: bidIncrementTable, Table: table}, 900, 25},
+		{"unsorted table lower tier", BidIncrementRule{Type: bidIncrementTable, Table: unsorted}, 99, 5},
+		{"unsorted table middle tier", BidIncrementRule{Type: bidIncrementTable, Table: unsorted}, 100, 10},
+		{"unsorted table open tier", BidIncrementRule{Type: bidIncrementTable, Table: unsorted}, 900, 25},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := minimumIncrement(tc.rule, tc.currentBid); got != tc.expected {
+				t.Fatalf("minimumIncrement(%d) = %d, expected %d", tc.currentBid, got, tc.expected)
+			}
+		})
+	}
+
+	if got := minimumBid(BidIncrementRule{Type: bidIncrementFixed, Value: 10}, 20, 0); got != 20 {
+		t.Fatalf("expected opening bid to be the starting bid, got %d", got)
+	}
+}
+
+// TestBidIncrementRuleFallsBackToSettings verifies auctions without a rule use the global one.
+func TestBidIncrementRuleFallsBackToSettings(t *testing.T) {
+	app := newTestApp(t)
+
+	insertSettingsRecord(t, app)
+	if _, err := app.DB().Update("settings", dbx.Params{
+		"bidIncrementType":  bidIncrementTable,
+		"bidIncrementTable": `[{"below":100,"step":5},{"step":25}]`,
+	}, nil).Execute(); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+	settings, err := GetSettings(app.App)
+	if err != nil {
+		t.Fatalf("GetSettings returned error: %v", err)
+	}
+	auction := createTestAuction(t, app, 10)
+
+	rule, err := bidIncrementRule(settings, auction)
+	if err != nil {
+		t.Fatalf("bidIncrementRule returned error: %v", err)
+	}
+	if got := minimumBid(rule, 10, 150); got != 175 {
+		t.Fatalf("expected global table to require 175, got %d", got)
+	}
+
+	auction.Set("bidIncrementType", bidIncrementFixed)
+	auction.Set("bidIncrementValue", 2)
+	rule, err = bidIncrementRule(settings, auction)
+	if err != nil {
+		t.Fatalf("bidIncrementRule returned error: %v", err)
+	}
+	if got := minimumBid(rule, 10, 150); got != 152 {
+		t.Fatalf("expected auction rule to require 152, got %d", got)
+	}
+}
+
+// TestSealedPrice verifies the price charged in first and second price sealed auctions.
+func TestSealedPrice(t *testing.T) {
+	ranked := []rankedBid{
+		{UserId: "c", Amount: 40, Timestamp: 3},
+		{UserId: "a", Amount: 70, Timestamp: 2},
+		{UserId: "b", Amount: 70, Timestamp: 1},
+	}
+	rankBids(ranked)
+	if ranked[0].UserId != "b" || ranked[1].UserId != "a" {
+		t.Fatalf("expected earliest of the highest bids to rank first, got %v", ranked)
+	}
+
+	if got := sealedPrice(auctionModeSealedFirstPrice, ranked, 10); got != 70 {
+		t.Fatalf("expected first price 70, got %d", got)
+	}
+	if got := sealedPrice(auctionModeSealedSecondPrice, ranked, 10); got != 70 {
+		t.Fatalf("expected tied second price 70, got %d", got)
+	}
+	if got := sealedPrice(auctionModeSealedSecondPrice, []rankedBid{{Amount: 70}, {Amount: 40}}, 10); got != 41 {
+		t.Fatalf("expected second price 41, got %d", got)
+	}
+	if got := sealedPrice(auctionModeSealedSecondPrice, []rankedBid{{Amount: 70}}, 10); got != 10 {
+		t.Fatalf("expected lone bid to pay the starting bid, got %d", got)
+	}
+	if got := sealedPrice(auctionModeSealedSecondPrice, []rankedBid{{Amount: 80}}, 0); got != 1 {
+		t.Fatalf("expected lone bid without a starting bid to pay the opening bid 1, got %d", got)
+	}
+}
+
+// TestFinishSealedAuction verifies hidden bids are revealed and charged on close.
+func TestFinishSealedAuction(t *testing.T) {
+	app := newTestApp(t)
+
+	winner := createTestUser(t, app, "winner@example.com", []string{"member"})
+	loser := createTestUser(t, app, "loser@example.com", []string{"member"})
+	setTestTokens(t, app, winner, 100)
+	setTestTokens(t, app, loser, 100)
+	auction := createTestAuction(t, app, 10)
+	auction.Set("mode", auctionModeSealedSecondPrice)
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+
+	placeTestBid(t, app, winner, auction.Id, `{"amount":60}`)
+	placeTestBid(t, app, loser, auction.Id, `{"amount":35}`)
+	assertReservedTokens(t, app, winner.Id, 60)
+	assertReservedTokens(t, app, loser.Id, 35)
+
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("winner") != "" || auction.GetInt("currentBid") != 0 {
+		t.Fatalf("expected sealed bids to stay hidden, got %s@%d", auction.GetString("winner"), auction.GetInt("currentBid"))
+	}
+
+	expireTestAuction(t, app, auction)
+	if err := finishAuction(app); err != nil {
+		t.Fatalf("finishAuction returned error: %v", err)
+	}
+
+	auction, err = app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("winner") != winner.Id || auction.GetInt("currentBid") != 36 {
+		t.Fatalf("expected winner to pay 36, got %s@%d", auction.GetString("winner"), auction.GetInt("currentBid"))
+	}
+	assertReservedTokens(t, app, winner.Id, 0)
+	assertReservedTokens(t, app, loser.Id, 0)
+	assertTokens(t, app, winner.Id, 64)
+	assertTokens(t, app, loser.Id, 100)
+}
+
+// TestUnitAwards verifies pay-as-bid and uniform pricing of multi-quantity auctions.
+func TestUnitAwards(t *testing.T) {
+	ranked := []rankedBid{{UserId: "a", Amount: 50}, {UserId: "b", Amount: 40}, {UserId: "c", Amount: 30}}
+
+	awards := unitAwards(pricingPayAsBid, ranked, 2)
+	if len(awards) != 2 || awards[0] != (auctionAward{UserId: "a", Amount: 50}) || awards[1] != (auctionAward{UserId: "b", Amount: 40}) {
+		t.Fatalf("unexpected pay-as-bid awards: %v", awards)
+	}
+	awards = unitAwards(pricingUniform, ranked, 2)
+	if len(awards) != 2 || awards[0].Amount != 40 || awards[1].Amount != 40 {
+		t.Fatalf("unexpected uniform awards: %v", awards)
+	}
+	awards = unitAwards(pricingUniform, ranked, 5)
+	if len(awards) != 3 || awards[0].Amount != 30 {
+		t.Fatalf("expected every bid to win when units exceed bids, got %v", awards)
+	}
+}
+
+// TestFinishMultiUnitAuction verifies each winner is charged and outbid bidders are released.
+func TestFinishMultiUnitAuction(t *testing.T) {
+	app := newTestApp(t)
+
+	first := createTestUser(t, app, "first@example.com", []string{"member"})
+	second := createTestUser(t, app, "second@example.com", []string{"member"})
+	third := createTestUser(t, app, "third@example.com", []string{"member"})
+	for _, user := range []*core.Record{first, second, third} {
+		setTestTokens(t, app, user, 100)
+	}
+	auction := createTestAuction(t, app, 10)
+	auction.Set("quantity", 2)
+	auction.Set("pricing", pricingUniform)
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+
+	placeTestBid(t, app, third, auction.Id, `{"amount":20}`)
+	placeTestBid(t, app, first, auction.Id, `{"amount":50}`)
+	placeTestBid(t, app, second, auction.Id, `{"amount":30}`)
+	assertReservedTokens(t, app, first.Id, 50)
+	assertReservedTokens(t, app, second.Id, 30)
+	assertReservedTokens(t, app, third.Id, 0)
+
+	expireTestAuction(t, app, auction)
+	if err := finishAuction(app); err != nil {
+		t.Fatalf("finishAuction returned error: %v", err)
+	}
+
+	assertReservedTokens(t, app, first.Id, 0)
+	assertReservedTokens(t, app, second.Id, 0)
+	assertTokens(t, app, first.Id, 70)
+	assertTokens(t, app, second.Id, 70)
+	assertTokens(t, app, third.Id, 100)
+
+	results, err := app.FindRecordsByFilter("auctionsResult", "auction = {:auctionId}", "", 0, 0, dbx.Params{"auctionId": auction.Id})
+	if err != nil {
+		t.Fatalf("failed to find auction results: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected a result per winner, got %d", len(results))
+	}
+}
+
+// createTestAuction inserts an ongoing auction ending in one hour.
+func createTestAuction(t *testing.T, app *pocketbase.PocketBase, startingBid int) *core.Record {
+	t.Helper()
+
+	collection, err := app.FindCollectionByNameOrId("auctions")
+	if err != nil {
+		t.Fatalf("failed to find auctions collection: %v", err)
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("itemName", "Test item")
+	record.Set("startingBid", startingBid)
+	record.Set("state", "ongoing")
+	record.Set("endTime", time.Now().UTC().Add(time.Hour))
+
+	if err := app.Save(record); err != nil {
+		t.Fatalf("failed to save auction record: %v", err)
+	}
+
+	return record
+}
+
+// assertReservedTokens fails the test when a user's reservedTokens differ from the expected value.
+func assertReservedTokens(t *testing.T, app *pocketbase.PocketBase, userId string, expected int) {
+	t.Helper()
+
+	user, err := app.FindRecordById("users", userId)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if got := user.GetInt("reservedTokens"); got != expected {
+		t.Fatalf("expected reservedTokens %d, got %d", expected, got)
+	}
+}
+
+// placeTestBid calls handleBid for the user and fails the test on error.
+func placeTestBid(t *testing.T, app *pocketbase.PocketBase, user *core.Record, auctionId string, body string) {
+	t.Helper()
+
+	e, _ := newTestRequestEvent(app, user, body, map[string]string{"id": auctionId})
+	if err := handleBid(e); err != nil {
+		t.Fatalf("handleBid returned error: %v", err)
+	}
+}
+
+// setTestTokens sets the token balance of a user by transferring the difference from the guild pool.
+func setTestTokens(t *testing.T, app *pocketbase.PocketBase, user *core.Record, tokens int) {
+	t.Helper()
+
+	current, err := app.FindRecordById("users", user.Id)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if err := transferTokens(app, user.Id, tokens-current.GetInt("tokens"), "Test tokens", ""); err != nil {
+		t.Fatalf("failed to transfer user tokens: %v", err)
+	}
+}
+
+// expireTestAuction moves the end of an auction into the past.
+func expireTestAuction(t *testing.T, app *pocketbase.PocketBase, auction *core.Record) {
+	t.Helper()
+
+	auction.Set("endTime", time.Now().UTC().Add(-time.Minute))
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+}
+
+// assertTokens fails the test when a user's tokens differ from the expected value.
+func assertTokens(t *testing.T, app *pocketbase.PocketBase, userId string, expected int) {
+	t.Helper()
+
+	user, err := app.FindRecordById("users", userId)
+	if err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	if got := user.GetInt("tokens"); got != expected {
+		t.Fatalf("expected tokens %d, got %d", expected, got)
+	}
+}
+
+// TestHandleBidBuyout verifies a bid at the buyout price finishes the auction immediately at that price.
+func TestHandleBidBuyout(t *testing.T) {
+	app := newTestApp(t)
+
+	leader := createTestUser(t, app, "leader@example.com", []string{"member"})
+	buyer := createTestUser(t, app, "buyer@example.com", []string{"member"})
+	setTestTokens(t, app, leader, 100)
+	setTestTokens(t, app, buyer, 100)
+	auction := createTestAuction(t, app, 10)
+	auction.Set("buyoutPrice", 50)
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+
+	placeTestBid(t, app, leader, auction.Id, `{"amount":20}`)
+	placeTestBid(t, app, buyer, auction.Id, `{"amount":60}`)
+
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("state") != "finished" || auction.GetString("winner") != buyer.Id || auction.GetInt("currentBid") != 50 {
+		t.Fatalf("expected buyer to win at 50, got %s %s@%d", auction.GetString("state"), auction.GetString("winner"), auction.GetInt("currentBid"))
+	}
+	assertTokens(t, app, buyer.Id, 50)
+	assertTokens(t, app, leader.Id, 100)
+	assertReservedTokens(t, app, buyer.Id, 0)
+	assertReservedTokens(t, app, leader.Id, 0)
+	if _, err := app.FindFirstRecordByData("auctionsResult", "winner", buyer.Id); err != nil {
+		t.Fatalf("expected an auction result for the buyer: %v", err)
+	}
+}
+
+// TestHandleBidBuyoutThroughProxyContest verifies a proxy contest reaching the buyout price sells the auction
+// at the buyout price to its winner, and that a buyout price the bids already passed no longer applies.
+func TestHandleBidBuyoutThroughProxyContest(t *testing.T) {
+	app := newTestApp(t)
+
+	leader := createTestUser(t, app, "leader@example.com", []string{"member"})
+	challenger := createTestUser(t, app, "challenger@example.com", []string{"member"})
+	buyer := createTestUser(t, app, "buyer@example.com", []string{"member"})
+	for _, user := range []*core.Record{leader, challenger, buyer} {
+		setTestTokens(t, app, user, 500)
+	}
+	newBuyoutAuction := func() *core.Record {
+		auction := createTestAuction(t, app, 10)
+		auction.Set("buyoutPrice", 100)
+		if err := app.Save(auction); err != nil {
+			t.Fatalf("failed to save auction: %v", err)
+		}
+		return auction
+	}
+	assertBuyout := func(auctionId string, winnerId string) {
+		t.Helper()
+		auction, err := app.FindRecordById("auctions", auctionId)
+		if err != nil {
+			t.Fatalf("failed to reload auction: %v", err)
+		}
+		if auction.GetString("state") != "finished" || auction.GetString("winner") != winnerId || auction.GetInt("currentBid") != 100 {
+			t.Fatalf("expected a buyout at 100, got %s %s@%d", auction.GetString("state"), auction.GetString("winner"), auction.GetInt("currentBid"))
+		}
+	}
+
+	// the challenger's maximum pushes the price past the buyout price
+	auction := newBuyoutAuction()
+	placeTestBid(t, app, leader, auction.Id, `{"amount":20,"maxAmount":150}`)
+	placeTestBid(t, app, challenger, auction.Id, `{"amount":30,"maxAmount":300}`)
+	assertBuyout(auction.Id, challenger.Id)
+	assertTokens(t, app, challenger.Id, 400)
+	assertTokens(t, app, leader.Id, 500)
+
+	// a standing maximum above the buyout price beats a later bid reaching it
+	auction = newBuyoutAuction()
+	placeTestBid(t, app, leader, auction.Id, `{"amount":20,"maxAmount":300}`)
+	placeTestBid(t, app, buyer, auction.Id, `{"amount":200}`)
+	assertBuyout(auction.Id, leader.Id)
+	assertTokens(t, app, leader.Id, 400)
+	assertTokens(t, app, buyer.Id, 500)
+
+	// once the bids passed the buyout price the auction carries on
+	auction = createTestAuction(t, app, 10)
+	placeTestBid(t, app, leader, auction.Id, `{"amount":120}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	auction.Set("buyoutPrice", 100)
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+	placeTestBid(t, app, buyer, auction.Id, `{"amount":130}`)
+	auction, err = app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("state") != "ongoing" || auction.GetString("winner") != buyer.Id || auction.GetInt("currentBid") != 130 {
+		t.Fatalf("expected a regular bid at 130, got %s %s@%d", auction.GetString("state"), auction.GetString("winner"), auction.GetInt("currentBid"))
+	}
+}
+
+// TestApplyAntiSnipe verifies the trigger window and the extension cap of the floating end.
+func TestApplyAntiSnipe(t *testing.T) {
+	app := newTestApp(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	cases := []struct {
+		name      string
+		rule      AntiSnipeRule
+		endIn     time.Duration
+		extended  int
+		expectEnd time.Duration
+	}{
+		{"disabled", AntiSnipeRule{ExtendMinutes: 5}, 2 * time.Minute, 0, 2 * time.Minute},
+		{"end already later", AntiSnipeRule{Enabled: true, ExtendMinutes: 5}, 30 * time.Minute, 0, 30 * time.Minute},
+		{"outside trigger window", AntiSnipeRule{Enabled: true, ExtendMinutes: 30, TriggerMinutes: 10}, 20 * time.Minute, 0, 20 * time.Minute},
+		{"inside trigger window", AntiSnipeRule{Enabled: true, ExtendMinutes: 5, TriggerMinutes: 10}, 2 * time.Minute, 0, 5 * time.Minute},
+		{"capped", AntiSnipeRule{Enabled: true, ExtendMinutes: 5, CapMinutes: 4}, 2 * time.Minute, 180, 3 * time.Minute},
+		{"cap used up", AntiSnipeRule{Enabled: true, ExtendMinutes: 5, CapMinutes: 4}, 2 * time.Minute, 240, 2 * time.Minute},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			auction := createTestAuction(t, app, 10)
+			auction.Set("endTime", now.Add(tc.endIn))
+			auction.Set("antiSnipeExtension", tc.extended)
+
+			applyAntiSnipe(tc.rule, auction, now)
+
+			if got := auction.GetDateTime("endTime").Time().Sub(now); got != tc.expectEnd {
+				t.Fatalf("expected the auction to end in %s, got %s", tc.expectEnd, got)
+			}
+		})
+	}
+}
+
+// TestAntiSnipeRuleOverridesSettings verifies per-auction anti-snipe settings take precedence over the global ones.
+func TestAntiSnipeRuleOverridesSettings(t *testing.T) {
+	app := newTestApp(t)
+
+	settings := &Settings{EnableFloatingEndOfAuction: true, FloatingEndOfAuctionMinutes: 5, FloatingEndCapMinutes: 60}
+	auction := createTestAuction(t, app, 10)
+	if rule := antiSnipeRule(settings, auction); !rule.Enabled || rule.ExtendMinutes != 5 || rule.CapMinutes != 60 {
+		t.Fatalf("expected the global rule, got %+v", rule)
+	}
+
+	auction.Set("antiSnipe", "enabled")
+	auction.Set("antiSnipeTriggerMinutes", 3)
+	if rule := antiSnipeRule(settings, auction); !rule.Enabled || rule.ExtendMinutes != 5 || rule.TriggerMinutes != 3 || rule.CapMinutes != 0 {
+		t.Fatalf("expected the auction rule with the global extension, got %+v", rule)
+	}
+
+	auction.Set("antiSnipe", "disabled")
+	if rule := antiSnipeRule(settings, auction); rule.Enabled {
+		t.Fatalf("expected anti-snipe to be disabled, got %+v", rule)
+	}
+}
+
+// TestHandleRetractBidRestoresLeader verifies a retracted bid hands the lead and reservation back to the previous leader.
+func TestHandleRetractBidRestoresLeader(t *testing.T) {
+	app := newTestApp(t)
+
+	enableTestBidRetraction(t, app, 5)
+	leader := createTestUser(t, app, "leader@example.com", []string{"member"})
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	setTestTokens(t, app, leader, 100)
+	setTestTokens(t, app, bidder, 10000)
+	auction := createTestAuction(t, app, 10)
+
+	placeTestBid(t, app, leader, auction.Id, `{"amount":20}`)
+	placeTestBid(t, app, bidder, auction.Id, `{"amount":9000}`)
+
+	e, _ := newTestRequestEvent(app, bidder, "", map[string]string{"id": auction.Id})
+	if err := handleRetractBid(e); err != nil {
+		t.Fatalf("handleRetractBid returned error: %v", err)
+	}
+
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("winner") != leader.Id || auction.GetInt("currentBid") != 20 {
+		t.Fatalf("expected the leader to hold the auction at 20, got %s@%d", auction.GetString("winner"), auction.GetInt("currentBid"))
+	}
+	assertReservedTokens(t, app, leader.Id, 20)
+	assertReservedTokens(t, app, bidder.Id, 0)
+	if bid, err := findBid(app.App, auction.Id, bidder.Id); err != nil || bid != nil {
+		t.Fatalf("expected the retracted bid to no longer stand, got %v (%v)", bid, err)
+	}
+
+	event, err := app.FindFirstRecordByData("auctionEvents", "auction", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to find auction event: %v", err)
+	}
+	if event.GetString("type") != auctionLogBidRetracted || event.GetString("actor") != bidder.Id {
+		t.Fatalf("expected a bidRetracted event by the bidder, got %q", event.GetString("type"))
+	}
+}
+
+// TestHandleRetractBidUndoesForcedProxyRaises verifies raises a retracted bid forced on the leader's maximum are undone,
+// while raises forced by other bids stay.
+func TestHandleRetractBidUndoesForcedProxyRaises(t *testing.T) {
+	app := newTestApp(t)
+
+	enableTestBidRetraction(t, app, 5)
+	leader := createTestUser(t, app, "leader@example.com", []string{"member"})
+	other := createTestUser(t, app, "other@example.com", []string{"member"})
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	setTestTokens(t, app, leader, 100)
+	setTestTokens(t, app, other, 100)
+	setTestTokens(t, app, bidder, 10000)
+	auction := createTestAuction(t, app, 10)
+
+	placeTestBid(t, app, leader, auction.Id, `{"amount":20,"maxAmount":100}`)
+	placeTestBid(t, app, other, auction.Id, `{"amount":30}`)
+	placeTestBid(t, app, bidder, auction.Id, `{"amount":9000}`)
+
+	e, _ := newTestRequestEvent(app, bidder, "", map[string]string{"id": auction.Id})
+	if err := handleRetractBid(e); err != nil {
+		t.Fatalf("handleRetractBid returned error: %v", err)
+	}
+
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("winner") != leader.Id || auction.GetInt("currentBid") != 31 {
+		t.Fatalf("expected the leader to hold the auction at 31, got %s@%d", auction.GetString("winner"), auction.GetInt("currentBid"))
+	}
+	assertReservedTokens(t, app, leader.Id, 31)
+	assertReservedTokens(t, app, bidder.Id, 0)
+	forced, err := app.FindAllRecords("bids", dbx.HashExp{"auction": auction.Id, "user": leader.Id, "amount": 100})
+	if err != nil || len(forced) != 1 || !forced[0].GetBool("retracted") {
+		t.Fatalf("expected the raise to 100 to be retracted, got %v (%v)", forced, err)
+	}
+}
+
+// TestHandleRetractBidAfterWindow verifies bids older than the retraction window stand.
+func TestHandleRetractBidAfterWindow(t *testing.T) {
+	app := newTestApp(t)
+
+	enableTestBidRetraction(t, app, 5)
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	setTestTokens(t, app, bidder, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, bidder, auction.Id, `{"amount":20}`)
+
+	bid, err := findBid(app.App, auction.Id, bidder.Id)
+	if err != nil || bid == nil {
+		t.Fatalf("failed to find bid: %v", err)
+	}
+	bid.Set("timestamp", time.Now().Add(-10*time.Minute).Unix())
+	if err := app.Save(bid); err != nil {
+		t.Fatalf("failed to age bid: %v", err)
+	}
+
+	e, _ := newTestRequestEvent(app, bidder, "", map[string]string{"id": auction.Id})
+	if err := handleRetractBid(e); err == nil {
+		t.Fatal("expected retracting an old bid to fail")
+	}
+	assertReservedTokens(t, app, bidder.Id, 20)
+}
+
+func enableTestBidRetraction(t *testing.T, app *pocketbase.PocketBase, minutes int) {
+	t.Helper()
+
+	insertSettingsRecord(t, app)
+	if _, err := app.DB().Update("settings", dbx.Params{"bidRetractionMinutes": minutes}, nil).Execute(); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+}
+
+// validateTestUser marks a test user as validated.
+func validateTestUser(t *testing.T, app *pocketbase.PocketBase, user *core.Record) {
+	t.Helper()
+
+	user.Set("validated", true)
+	if err := app.Save(user); err != nil {
+		t.Fatalf("failed to validate user: %v", err)
+	}
+}
+
+// getTestBidHistory returns the bid history of an auction as seen by the given user.
+func getTestBidHistory(t *testing.T, app *pocketbase.PocketBase, user *core.Record, auctionId string) []BidHistoryEntry {
+	t.Helper()
+
+	e, rec := newTestRequestEvent(app, user, "", map[string]string{"id": auctionId})
+	if err := getBidHistory(e); err != nil {
+		t.Fatalf("getBidHistory returned error: %v", err)
+	}
+	history := []BidHistoryEntry{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &history); err != nil {
+		t.Fatalf("failed to decode history: %v", err)
+	}
+	return history
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// closeDueAuction closes an auction once its end time has passed and reports whether this call closed it.
+// An auction extended after its timer was armed gets a new timer instead.
+func closeDueAuction(app core.App, auctionId string) (bool, error) {
+	closed := false
+	err := app.RunInTransaction(func(tx core.App) error {
+		auction, err := findOngoingAuction(tx, auctionId)
+		if err != nil || auction == nil {
+			return err
+		}
+		if endTime := auction.GetDateTime("endTime").Time(); endTime.After(time.Now()) {
+			scheduleAuctionClose(auctionId, endTime)
+			return nil
+		}
+		closed, err = claimAuctionClose(tx, auction)
+		return err
+	})
+	return closed, err
+}
+
+// closeAuctionNow closes an ongoing or paused auction before its end time on behalf of a manager.
+// It returns the logged close event, nil when the auction was not open anymore.
+func closeAuctionNow(app core.App, auctionId string, actorId string) (*core.Record, error) {
+	var event *core.Record
+	err := app.RunInTransaction(func(tx core.App) error {
+		auction, err := tx.FindRecordById("auctions", auctionId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		if state := auction.GetString("state"); state != "ongoing" && state != "paused" {
+			return nil
+		}
+		scheduledEnd := auction.GetDateTime("endTime")
+		auction.Set("endTime", types.NowDateTime())
+		auction.Set("pausedAt", nil)
+		closed, err := claimAuctionClose(tx, auction)
+		if err != nil || !closed {
+			return err
+		}
+		event, err = logAuctionEvent(tx, auctionId, auctionLogClosed, actorId, map[string]any{"scheduledEnd": scheduledEnd})
+		return err
+	})
+	return event, err
+}
+
+// findOngoingAuction loads an auction that still takes bids, nil when it is gone or no longer ongoing.
+func findOngoingAuction(app core.App, auctionId string) (*core.Record, error) {
+	auction, err := app.FindRecordById("auctions", auctionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if auction.GetString("state") != "ongoing" {
+		return nil, nil
+	}
+	return auction, nil
+}
+
+// claimAuctionClose takes the auction out of its ongoing or paused state with a conditional update before closing it,
+// so only one caller settles it even when several processes share the database.
+func claimAuctionClose(app core.App, auction *core.Record) (bool, error) {
+	res, err := app.DB().Update(
+		"auctions",
+		dbx.Params{"state": "finished"},
+		dbx.HashExp{"id": auction.Id, "state": auction.GetString("state")},
+	).Execute()
+	if err != nil {
+		return false, err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+	return true, closeAuction(app, auction)
+}
+
+// closeAuction finishes an auction: it settles the awards, releases reservations and records the results.
+// Auctions whose bids stay below the reserve price close as unsold.
+func closeAuction(app core.App, auction *core.Record) error {
+	awards, err := resolveAwards(app, auction)
+	if err != nil {
+		return err
+	}
+	if err := releaseReservations(app, auction.Id); err != nil {
+		return err
+	}
+	if reservePrice := auction.GetInt("reservePrice"); reservePrice > 0 {
+		awards = applyReservePrice(auction, awards, reservePrice)
+		if len(awards) == 0 {
+			return closeUnsoldAuction(app, auction)
+		}
+	}
+	auction.Set("state", "finished")
+
+	if len(awards) == 0 {
+		coll, err := app.FindCachedCollectionByNameOrId("auctionsResult")
+		if err != nil {
+			return err
+		}
+		resultRecord := core.NewRecord(coll)
+		resultRecord.Set("auction", auction.Id)
+		if err := app.Save(resultRecord); err != nil {
+			return err
+		}
+	}
+	winnerIds := make([]string, len(awards))
+	for i, award := range awards {
+		winnerIds[i] = award.UserId
+	}
+	for _, award := range awards {
+		if _, err := awardAuction(app, auction.Id, award.UserId, award.Amount, winnerIds); err != nil {
+			return err
+		}
+	}
+
+	if err := app.Save(auction); err != nil {
+		return err
+	}
+	if err := announceAuction(app, auctionEventFinished, auction); err != nil {
+		return err
+	}
+	for _, award := range awards {
+		if err := notifyUser(app, award.UserId, NotificationEvent{
+			Type:    eventWon,
+			Auction: auction.Id,
+			Amount:  award.Amount,
+			Data:    map[string]any{"itemName": auction.GetString("itemName")},
+		}); err != nil {
+			return err
+		}
+	}
+	return notifyRole(app, "manager", NotificationEvent{
+		Type:    eventAuctionEnded,
+		Auction: auction.Id,
+		Data:    map[string]any{"itemName": auction.GetString("itemName")},
+	})
+}
+
+// closeUnsoldAuction closes an auction that missed its reserve price without charging anybody.
+func closeUnsoldAuction(app core.App, auction *core.Record) error {
+	auction.Set("state", "unsold")
+	auction.Set("winner", "")
+	auction.Set("winners", []string{})
+	if err := app.Save(auction); err != nil {
+		return err
+	}
+
+	coll, err := app.FindCachedCollectionByNameOrId("auctionsResult")
+	if err != nil {
+		return err
+	}
+	resultRecord := core.NewRecord(coll)
+	resultRecord.Set("auction", auction.Id)
+	resultRecord.Set("noSale", true)
+	if err := app.Save(resultRecord); err != nil {
+		return err
+	}
+
+	if err := announceAuction(app, auctionEventUnsold, auction); err != nil {
+		return err
+	}
+	return notifyRole(app, "manager", NotificationEvent{
+		Type:    eventAuctionUnsold,
+		Auction: auction.Id,
+		Amount:  auction.GetInt("currentBid"),
+		Data:    map[string]any{"itemName": auction.GetString("itemName")},
+	})
+}
+
+// awardAuction charges a user the price of an auction unit and records the win in auctionsResult.
+// With zero-sum distribution the price is shared right away among the members who are not one of the auction winners.
+func awardAuction(app core.App, auctionId string, userId string, amount int, winnerIds []string) (*core.Record, error) {
+	if err := transferTokens(app, userId, -amount, "Win in auction", ""); err != nil {
+		return nil, err
+	}
+	shares, err := distributeZeroSum(app, auctionId, winnerIds, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	coll, err := app.FindCachedCollectionByNameOrId("auctionsResult")
+	if err != nil {
+		return nil, err
+	}
+	resultRecord := core.NewRecord(coll)
+	resultRecord.Set("auction", auctionId)
+	resultRecord.Set("winner", userId)
+	resultRecord.Set("amount", amount)
+	if len(shares) > 0 {
+		resultRecord.Set("distribution", shares)
+	}
+	if err := app.Save(resultRecord); err != nil {
+		return nil, err
+	}
+	return resultRecord, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestCloseDueAuctionIsIdempotent verifies closing an auction twice settles it only once.
+func TestCloseDueAuctionIsIdempotent(t *testing.T) {
+	app := newTestApp(t)
+
+	winner := createTestUser(t, app, "winner@example.com", []string{"member"})
+	setTestTokens(t, app, winner, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, winner, auction.Id, `{"amount":30}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	expireTestAuction(t, app, auction)
+
+	for i, expected := range []bool{true, false} {
+		closed, err := closeDueAuction(app.App, auction.Id)
+		if err != nil {
+			t.Fatalf("closeDueAuction returned error: %v", err)
+		}
+		if closed != expected {
+			t.Fatalf("call %d: expected closed %v, got %v", i+1, expected, closed)
+		}
+	}
+
+	assertTokens(t, app, winner.Id, 70)
+	assertReservedTokens(t, app, winner.Id, 0)
+	count, err := app.CountRecords("auctionsResult")
+	if err != nil {
+		t.Fatalf("failed to count auction results: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected a single auction result, got %d", count)
+	}
+}
+
+// TestCloseDueAuctionConcurrently ensures parallel closers settle an auction exactly once.
+func TestCloseDueAuctionConcurrently(t *testing.T) {
+	app := newTestApp(t)
+
+	winner := createTestUser(t, app, "winner@example.com", []string{"member"})
+	setTestTokens(t, app, winner, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, winner, auction.Id, `{"amount":30}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	expireTestAuction(t, app, auction)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	closedCount := 0
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			closed, err := closeDueAuction(app.App, auction.Id)
+			if err != nil {
+				t.Errorf("closeDueAuction returned error: %v", err)
+				return
+			}
+			if closed {
+				mu.Lock()
+				closedCount++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if closedCount != 1 {
+		t.Fatalf("expected exactly one closer, got %d", closedCount)
+	}
+	assertTokens(t, app, winner.Id, 70)
+}
+
+// TestCloseDueAuctionLeavesRunningAuction verifies auctions before their end time stay open.
+func TestCloseDueAuctionLeavesRunningAuction(t *testing.T) {
+	app := newTestApp(t)
+
+	auction := createTestAuction(t, app, 10)
+	closed, err := closeDueAuction(app.App, auction.Id)
+	if err != nil {
+		t.Fatalf("closeDueAuction returned error: %v", err)
+	}
+	if closed {
+		t.Fatalf("expected a running auction to stay open")
+	}
+}
+
+// TestHandleCloseAuction verifies managers can close an auction before its end time.
+func TestHandleCloseAuction(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	member := createTestUser(t, app, "member@example.com", []string{"member"})
+	setTestTokens(t, app, member, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, member, auction.Id, `{"amount":25}`)
+
+	e, _ := newTestRequestEvent(app, member, "", map[string]string{"id": auction.Id})
+	if err := handleCloseAuction(e); err == nil {
+		t.Fatalf("expected members to be refused")
+	}
+
+	e, _ = newTestRequestEvent(app, manager, "", map[string]string{"id": auction.Id})
+	if err := handleCloseAuction(e); err != nil {
+		t.Fatalf("handleCloseAuction returned error: %v", err)
+	}
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("state") != "finished" || auction.GetString("winner") != member.Id {
+		t.Fatalf("expected member to win the closed auction, got state %q", auction.GetString("state"))
+	}
+	assertTokens(t, app, member.Id, 75)
+}
+
+// TestHandleCloseAuctionWhilePaused verifies a paused auction can be closed without resuming it first.
+func TestHandleCloseAuctionWhilePaused(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	member := createTestUser(t, app, "member@example.com", []string{"member"})
+	setTestTokens(t, app, member, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, member, auction.Id, `{"amount":25}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if err := pauseAuction(app, auction); err != nil {
+		t.Fatalf("pauseAuction returned error: %v", err)
+	}
+
+	e, _ := newTestRequestEvent(app, manager, "", map[string]string{"id": auction.Id})
+	if err := handleCloseAuction(e); err != nil {
+		t.Fatalf("handleCloseAuction returned error: %v", err)
+	}
+	auction, err = app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("state") != "finished" || auction.GetString("winner") != member.Id || !auction.GetDateTime("pausedAt").IsZero() {
+		t.Fatalf("expected the paused auction to finish, got state %q", auction.GetString("state"))
+	}
+	assertTokens(t, app, member.Id, 75)
+	assertReservedTokens(t, app, member.Id, 0)
+}
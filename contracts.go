@@ -1,5 +1,7 @@
 package main
 
+import "github.com/pocketbase/pocketbase/tools/types"
+
 type BidStruct struct {
 	Amount int `json:"amount"`
 }
@@ -9,11 +11,39 @@ type TokenHealtCheck struct {
 	UserResults []TokenHealtCheckUser `json:"userResults"`
 }
 type TokenHealtCheckUser struct {
-	State             string `json:"state"`
-	User              string `json:"user"`
-	UserTokens        int    `json:"userTokens"`
-	TransactionTokens int    `json:"transactionTokens"`
-	Differece         int    `json:"differece"`
+	State             string                       `json:"state"`
+	User              string                       `json:"user"`
+	UserTokens        int                          `json:"userTokens"`
+	TransactionTokens int                          `json:"transactionTokens"`
+	LedgerTokens      int                          `json:"ledgerTokens"`
+	Differece         int                          `json:"differece"`
+	ReservedTokens    int                          `json:"reservedTokens"`
+	EscrowTokens      int                          `json:"escrowTokens"`
+	ExpectedReserved  int                          `json:"expectedReserved"`
+	Reservations      []TokenHealtCheckReservation `json:"reservations,omitempty"`
+}
+type TokenHealtCheckReservation struct {
+	Auction  string `json:"auction"`
+	Issue    string `json:"issue"`
+	Reserved int    `json:"reserved"`
+	Expected int    `json:"expected"`
+}
+
+type BidHistoryEntry struct {
+	Id        string `json:"id"`
+	User      string `json:"user"`
+	UserName  string `json:"userName"`
+	Amount    int    `json:"amount"`
+	Source    string `json:"source"`
+	Retracted bool   `json:"retracted"`
+	Created   string `json:"created"`
+}
+
+type HealthCheckRepairChange struct {
+	User    string `json:"user"`
+	Kind    string `json:"kind"`
+	Auction string `json:"auction,omitempty"`
+	Amount  int    `json:"amount"`
 }
 
 type ChangeTokens struct {
@@ -21,17 +51,85 @@ type ChangeTokens struct {
 	Amount int    `json:"amount"`
 }
 
+type AuctionEventMessage struct {
+	Id      string `json:"id"`
+	Auction string `json:"auction"`
+	Type    string `json:"type"`
+	Actor   string `json:"actor"`
+}
+
+type AuctionUpdateMessage struct {
+	Auction     string   `json:"auction"`
+	State       string   `json:"state"`
+	CurrentBid  int      `json:"currentBid"`
+	Winner      string   `json:"winner"`
+	Winners     []string `json:"winners"`
+	WinnerNames []string `json:"winnerNames"`
+	EndTime     string   `json:"endTime"`
+}
+
+type NotificationEvent struct {
+	Type    string         `json:"type"`
+	Auction string         `json:"auction,omitempty"`
+	Amount  int            `json:"amount,omitempty"`
+	Actor   string         `json:"actor,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+type NotificationPreferences struct {
+	Events     map[string]map[string]bool `json:"events"`
+	MutedUntil string                     `json:"mutedUntil"`
+}
+
 type Settings struct {
-	NameSynchronization           bool   `db:"nameSynchronization"`
-	SynchronizationType           string `db:"synchronizationType"`
-	SynchronizationUrl            string `db:"synchronizationUrl"`
-	SynchronizationClient         string `db:"synchronizationClient"`
-	SynchronizationPassword       string `db:"synchronizationPassword"`
-	SynchronizationDiscordGuildId string `db:"synchronizationDiscordGuildId"`
-	EnableFloatingEndOfAuction    bool   `db:"enableFloatingEndOfAuction"`
-	FloatingEndOfAuctionMinutes   int    `db:"floatingEndOfAuctionMinutes"`
-	EnableTLDBAdapterSync         bool   `db:"enableTLDBAdapterSync"`
-	TldbAdapterUrl                string `db:"tldbAdapterUrl"`
+	NameSynchronization           bool                    `db:"nameSynchronization"`
+	SynchronizationType           string                  `db:"synchronizationType"`
+	SynchronizationUrl            string                  `db:"synchronizationUrl"`
+	SynchronizationClient         string                  `db:"synchronizationClient"`
+	SynchronizationPassword       string                  `db:"synchronizationPassword"`
+	SynchronizationDiscordGuildId string                  `db:"synchronizationDiscordGuildId"`
+	EnableFloatingEndOfAuction    bool                    `db:"enableFloatingEndOfAuction"`
+	FloatingEndOfAuctionMinutes   int                     `db:"floatingEndOfAuctionMinutes"`
+	EnableTLDBAdapterSync         bool                    `db:"enableTLDBAdapterSync"`
+	TldbAdapterUrl                string                  `db:"tldbAdapterUrl"`
+	ProxyReserveMaximum           bool                    `db:"proxyReserveMaximum"`
+	BidIncrementType              string                  `db:"bidIncrementType"`
+	BidIncrementValue             int                     `db:"bidIncrementValue"`
+	BidIncrementTable             types.JSONRaw           `db:"bidIncrementTable"`
+	EnableDiscordWebhook          bool                    `db:"enableDiscordWebhook"`
+	DiscordWebhookUrl             string                  `db:"discordWebhookUrl"`
+	DiscordMentionUsers           bool                    `db:"discordMentionUsers"`
+	EndingNotificationMinutes     int                     `db:"endingNotificationMinutes"`
+	FloatingEndTriggerMinutes     int                     `db:"floatingEndTriggerMinutes"`
+	FloatingEndCapMinutes         int                     `db:"floatingEndCapMinutes"`
+	BidRetractionMinutes          int                     `db:"bidRetractionMinutes"`
+	AnonymousBidders              bool                    `db:"anonymousBidders"`
+	DecaySchedule                 string                  `db:"decaySchedule"`
+	DecayPercentage               float64                 `db:"decayPercentage"`
+	DecayFloor                    int                     `db:"decayFloor"`
+	DecayExemptRoles              types.JSONArray[string] `db:"decayExemptRoles"`
+	DecayRounding                 string                  `db:"decayRounding"`
+	DecayLastRun                  types.DateTime          `db:"decayLastRun"`
+	ZeroSumDistribution           bool                    `db:"zeroSumDistribution"`
+	ZeroSumAttendees              types.JSONArray[string] `db:"zeroSumAttendees"`
+	ZeroSumRemainder              string                  `db:"zeroSumRemainder"`
+}
+
+type BidIncrementRule struct {
+	Type  string             `json:"type"`
+	Value int                `json:"value"`
+	Table []BidIncrementTier `json:"table"`
+}
+type BidIncrementTier struct {
+	Below int `json:"below"`
+	Step  int `json:"step"`
+}
+
+type AntiSnipeRule struct {
+	Enabled        bool `json:"enabled"`
+	ExtendMinutes  int  `json:"extendMinutes"`
+	TriggerMinutes int  `json:"triggerMinutes"`
+	CapMinutes     int  `json:"capMinutes"`
 }
 
 type TLDBAdapterResponse struct {
@@ -43,3 +141,28 @@ type TLDBAdapterItem struct {
 	Icon   string `json:"icon"`
 	Rarity int    `json:"rarity"`
 }
+
+type DiscordMessage struct {
+	Content         string                  `json:"content,omitempty"`
+	Embeds          []DiscordEmbed          `json:"embeds,omitempty"`
+	AllowedMentions *DiscordAllowedMentions `json:"allowed_mentions,omitempty"`
+}
+type DiscordAllowedMentions struct {
+	Users []string `json:"users"`
+}
+type DiscordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color,omitempty"`
+	Thumbnail   *DiscordEmbedImage  `json:"thumbnail,omitempty"`
+	Fields      []DiscordEmbedField `json:"fields,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"`
+}
+type DiscordEmbedImage struct {
+	Url string `json:"url"`
+}
+type DiscordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
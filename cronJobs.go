@@ -14,57 +14,63 @@ import (
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/types"
 )
 
-// finishAuction closes expired auctions, updates winners, and posts notifications.
-func finishAuction(app *pocketbase.PocketBase) error {
+// finishAuction closes expired auctions that their close timer missed.
+func finishAuction(app core.App) error {
 	records, err := app.FindRecordsByFilter("auctions", "state = 'ongoing' && endTime < @now", "", 0, 0, nil)
 	if err != nil {
 		return err
 	}
-	if len(records) == 0 {
-		return nil
+	for _, record := range records {
+		if _, err := closeDueAuction(app, record.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// notifyEndingAuctions reminds users who favourited an auction that it enters its last minutes
+// and announces it on Discord, once per auction.
+func notifyEndingAuctions(app *pocketbase.PocketBase) error {
+	settings, err := GetSettings(app)
+	if err != nil {
+		return err
+	}
+	if settings.EndingNotificationMinutes <= 0 {
+		return nil
+	}
+	endingBefore := types.NowDateTime().Add(time.Duration(settings.EndingNotificationMinutes) * time.Minute)
+	records, err := app.FindRecordsByFilter(
+		"auctions",
+		"state = 'ongoing' && endingAnnounced = false && endTime <= {:endingBefore}",
+		"",
+		0,
+		0,
+		dbx.Params{"endingBefore": endingBefore},
+	)
+	if err != nil {
+		return err
 	}
 	return app.RunInTransaction(func(tx core.App) error {
 		for _, record := range records {
-			record.Set("state", "finished")
-
-			coll, err := tx.FindCachedCollectionByNameOrId("auctionsResult")
-			if err != nil {
-				return err
-			}
-			resultRecord := core.NewRecord(coll)
-			resultRecord.Set("auction", record.Id)
-			userId := ""
-			if record.GetString("winner") != "" {
-				userRecord, err := tx.FindRecordById("users", record.GetString("winner"))
-				if err != nil {
-					return err
-				}
-
-				userRecord.Set("reservedTokens", userRecord.GetInt("reservedTokens")-record.GetInt("currentBid"))
-				userRecord.Set("tokens", userRecord.GetInt("tokens")-record.GetInt("currentBid"))
-				if err := createTransactionRecord(tx, userRecord.Id, -record.GetInt("currentBid"), "Win in auction", ""); err != nil {
+			for _, userId := range record.GetStringSlice("favourites") {
+				if err := notifyUser(tx, userId, NotificationEvent{
+					Type:    eventAuctionEnding,
+					Auction: record.Id,
+					Data:    map[string]any{"itemName": record.GetString("itemName"), "minutes": settings.EndingNotificationMinutes},
+				}); err != nil {
 					return err
 				}
-				if err := tx.Save(userRecord); err != nil {
-					return err
-				}
-				userId = userRecord.Id
 			}
-
-			err = tx.Save(record)
-			if err != nil {
+			if err := announceAuction(tx, auctionEventEnding, record); err != nil {
 				return err
 			}
-			if err := tx.Save(resultRecord); err != nil {
+			record.Set("endingAnnounced", true)
+			if err := tx.Save(record); err != nil {
 				return err
 			}
-			if userId != "" {
-				notifyUser(userId, "You won the auction")
-			}
-			notifyRole("manager", "Auction has ended")
 		}
 		return nil
 	})
@@ -172,28 +178,51 @@ func updateUserNames(app *pocketbase.PocketBase) error {
 	return nil
 }
 
-// runTokenHealthCheck reconciles token balances with transactions and stores a report.
+// runTokenHealthCheck reconciles token balances with transactions and reserved tokens with the auctions
+// they are held for, and stores a report.
 func runTokenHealthCheck(app *pocketbase.PocketBase) error {
 	records, err := app.FindAllRecords("users")
 	if err != nil {
 		return err
 	}
+	settings, err := GetSettings(app)
+	if err != nil {
+		return err
+	}
+	expected, active, err := expectedReservations(app, settings)
+	if err != nil {
+		return err
+	}
+	holdings, err := findEscrowHoldings(app)
+	if err != nil {
+		return err
+	}
+	issues := reservationIssues(holdings, expected, active)
+	escrow := map[string]int{}
+	for _, holding := range holdings {
+		escrow[holding.User] += holding.Amount
+	}
 	isError := false
 	userResults := []TokenHealtCheckUser{}
 	for _, record := range records {
 		userTokens := record.GetInt("tokens")
-		transactionRecords, err := app.FindRecordsByFilter("transactions", "user = {:userId}", "", 0, 0, dbx.Params{"userId": record.Id})
+		transactionTokens, err := sumTransactions(app, record.Id)
 		if err != nil {
 			return err
 		}
-		transactionTokens := 0
-
-		for _, transactionRecord := range transactionRecords {
-			transactionTokens += transactionRecord.GetInt("amount")
+		wallet, err := ledgerBalance(app, ledgerWallet, dbx.HashExp{"user": record.Id})
+		if err != nil {
+			return err
 		}
+		// the cached balance must match the ledger it is derived from
+		ledgerTokens := wallet + escrow[record.Id]
 		differece := userTokens - transactionTokens
+		expectedReserved := 0
+		for _, amount := range expected[record.Id] {
+			expectedReserved += amount.Min
+		}
 		state := "ok"
-		if differece != 0 {
+		if differece != 0 || userTokens != ledgerTokens || record.GetInt("reservedTokens") != escrow[record.Id] || len(issues[record.Id]) > 0 {
 			state = "error"
 			isError = true
 		}
@@ -202,7 +231,12 @@ func runTokenHealthCheck(app *pocketbase.PocketBase) error {
 			User:              record.Id,
 			UserTokens:        userTokens,
 			TransactionTokens: transactionTokens,
+			LedgerTokens:      ledgerTokens,
 			Differece:         differece,
+			ReservedTokens:    record.GetInt("reservedTokens"),
+			EscrowTokens:      escrow[record.Id],
+			ExpectedReserved:  expectedReserved,
+			Reservations:      issues[record.Id],
 		})
 	}
 
@@ -230,7 +264,7 @@ func runTokenHealthCheck(app *pocketbase.PocketBase) error {
 		return err
 	}
 	if isError {
-		notifyRole("admin", "Token health check failed")
+		return notifyRole(app, "admin", NotificationEvent{Type: eventHealthCheckFailure})
 	}
 	return nil
 }
@@ -326,7 +360,5 @@ func getTLDBItems(app *pocketbase.PocketBase) error {
 		}
 	}
 	// Notify that items have been updated
-	notifyRole("admin", "Items have been updated from TLDB")
-
-	return nil
+	return notifyRole(app, "admin", NotificationEvent{Type: eventItemsUpdated})
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pocketbase/pocketbase"
+)
+
+// TestTokenHealthCheckReconcilesReservations verifies reservations are checked against the auctions they are held for.
+func TestTokenHealthCheckReconcilesReservations(t *testing.T) {
+	app := newTestApp(t)
+
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	other := createTestUser(t, app, "other@example.com", []string{"member"})
+	setTestTokens(t, app, bidder, 100)
+	setTestTokens(t, app, other, 100)
+	auction := createTestAuction(t, app, 10)
+	finished := createTestAuction(t, app, 10)
+	placeTestBid(t, app, bidder, auction.Id, `{"amount":20}`)
+	placeTestBid(t, app, other, finished.Id, `{"amount":15}`)
+
+	if err := runTokenHealthCheck(app); err != nil {
+		t.Fatalf("runTokenHealthCheck returned error: %v", err)
+	}
+	if report := latestTestHealthCheck(t, app); report.State != "ok" {
+		t.Fatalf("expected consistent reservations to pass, got %+v", report)
+	}
+
+	// tokens left in escrow of a finished auction and a reservation above the leading bid
+	finished.Set("state", "finished")
+	if err := app.Save(finished); err != nil {
+		t.Fatalf("failed to finish auction: %v", err)
+	}
+	if err := moveToEscrow(app, auction.Id, bidder.Id, 5); err != nil {
+		t.Fatalf("moveToEscrow returned error: %v", err)
+	}
+
+	if err := runTokenHealthCheck(app); err != nil {
+		t.Fatalf("runTokenHealthCheck returned error: %v", err)
+	}
+	report := latestTestHealthCheck(t, app)
+	if report.State != "error" {
+		t.Fatalf("expected the health check to fail, got %q", report.State)
+	}
+	issues := map[string]TokenHealtCheckReservation{}
+	for _, result := range report.UserResults {
+		for _, reservation := range result.Reservations {
+			issues[result.User] = reservation
+		}
+	}
+	if issue := issues[bidder.Id]; issue.Issue != "mismatch" || issue.Reserved != 25 || issue.Expected != 20 {
+		t.Fatalf("expected a mismatched reservation for the bidder, got %+v", issue)
+	}
+	if issue := issues[other.Id]; issue.Issue != "orphaned" || issue.Auction != finished.Id || issue.Reserved != 15 {
+		t.Fatalf("expected an orphaned reservation for the other user, got %+v", issue)
+	}
+}
+
+// TestReservationIssues verifies negative, orphaned and missing reservations are reported.
+func TestReservationIssues(t *testing.T) {
+	holdings := []escrowHolding{
+		{User: "a", Auction: "ongoing", Amount: -5},
+		{User: "b", Auction: "", Amount: 10},
+	}
+	expected := map[string]map[string]reservationRange{
+		"c": {"ongoing": {Min: 30, Max: 30}},
+	}
+	issues := reservationIssues(holdings, expected, map[string]bool{"ongoing": true})
+
+	if len(issues["a"]) != 1 || issues["a"][0].Issue != "negative" {
+		t.Fatalf("expected a negative reservation, got %+v", issues["a"])
+	}
+	if len(issues["b"]) != 1 || issues["b"][0].Issue != "orphaned" {
+		t.Fatalf("expected an orphaned reservation, got %+v", issues["b"])
+	}
+	if len(issues["c"]) != 1 || issues["c"][0].Issue != "missing" || issues["c"][0].Expected != 30 {
+		t.Fatalf("expected a missing reservation, got %+v", issues["c"])
+	}
+}
+
+// latestTestHealthCheck decodes the most recent token health check report.
+func latestTestHealthCheck(t *testing.T, app *pocketbase.PocketBase) TokenHealtCheck {
+	t.Helper()
+
+	records, err := app.FindRecordsByFilter("tokenHealthChecks", "", "-created", 1, 0)
+	if err != nil || len(records) == 0 {
+		t.Fatalf("failed to find health check: %v", err)
+	}
+	report := TokenHealtCheck{}
+	if err := json.Unmarshal([]byte(records[0].GetString("result")), &report); err != nil {
+		t.Fatalf("failed to decode health check: %v", err)
+	}
+	return report
+}
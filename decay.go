@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	decayWeekly  = "weekly"
+	decayMonthly = "monthly"
+)
+
+const (
+	decayRoundDown    = "down"
+	decayRoundUp      = "up"
+	decayRoundNearest = "nearest"
+)
+
+// decayDue reports whether the configured decay has not run yet in the current week or month.
+func decayDue(settings *Settings, now time.Time) bool {
+	if settings.DecayPercentage <= 0 {
+		return false
+	}
+	if settings.DecayLastRun.IsZero() {
+		return settings.DecaySchedule == decayWeekly || settings.DecaySchedule == decayMonthly
+	}
+	lastRun := settings.DecayLastRun.Time()
+	switch settings.DecaySchedule {
+	case decayWeekly:
+		year, week := now.ISOWeek()
+		lastYear, lastWeek := lastRun.ISOWeek()
+		return year != lastYear || week != lastWeek
+	case decayMonthly:
+		return now.Year() != lastRun.Year() || now.Month() != lastRun.Month()
+	default:
+		return false
+	}
+}
+
+// decayAmount returns how many of the available tokens decay, never taking the balance below the floor.
+func decayAmount(settings *Settings, available int) int {
+	if available <= settings.DecayFloor {
+		return 0
+	}
+	exact := float64(available) * settings.DecayPercentage / 100
+	var amount int
+	switch settings.DecayRounding {
+	case decayRoundUp:
+		amount = int(math.Ceil(exact))
+	case decayRoundNearest:
+		amount = int(math.Round(exact))
+	default:
+		amount = int(math.Floor(exact))
+	}
+	return min(amount, available-settings.DecayFloor)
+}
+
+// runTokenDecay removes the configured percentage of available tokens from every user once per decay period.
+// Tokens reserved for ongoing auctions are left alone, so the decay runs regardless of open bids.
+func runTokenDecay(app core.App) error {
+	settings, err := GetSettings(app)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if !decayDue(settings, now) {
+		return nil
+	}
+	note := fmt.Sprintf("Scheduled DKP decay: %g%% (%s)", settings.DecayPercentage, settings.DecaySchedule)
+
+	return app.RunInTransaction(func(tx core.App) error {
+		users, err := tx.FindAllRecords("users")
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if slices.ContainsFunc(user.GetStringSlice("role"), func(role string) bool {
+				return slices.Contains(settings.DecayExemptRoles, role)
+			}) {
+				continue
+			}
+			amount := decayAmount(settings, user.GetInt("tokens")-user.GetInt("reservedTokens"))
+			if amount <= 0 {
+				continue
+			}
+			if err := transferTokens(tx, user.Id, -amount, note, ""); err != nil {
+				return err
+			}
+			if err := notifyUser(tx, user.Id, NotificationEvent{
+				Type:   eventTokenChange,
+				Amount: -amount,
+				Data:   map[string]any{"reason": note},
+			}); err != nil {
+				return err
+			}
+		}
+
+		settingsRecords, err := tx.FindAllRecords("settings")
+		if err != nil {
+			return err
+		}
+		for _, record := range settingsRecords {
+			record.Set("decayLastRun", now)
+			if err := tx.Save(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
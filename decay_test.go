@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// TestDecayAmount verifies the rounding modes and that decay never crosses the floor.
+func TestDecayAmount(t *testing.T) {
+	tests := []struct {
+		rounding  string
+		floor     int
+		available int
+		expected  int
+	}{
+		{decayRoundDown, 0, 25, 2},
+		{decayRoundUp, 0, 25, 3},
+		{decayRoundNearest, 0, 25, 3},
+		{decayRoundNearest, 0, 24, 2},
+		{"", 0, 25, 2},
+		{decayRoundUp, 20, 25, 3},
+		{decayRoundUp, 24, 25, 1},
+		{decayRoundUp, 30, 25, 0},
+	}
+	for _, tt := range tests {
+		settings := &Settings{DecayPercentage: 10, DecayRounding: tt.rounding, DecayFloor: tt.floor}
+		if got := decayAmount(settings, tt.available); got != tt.expected {
+			t.Fatalf("decayAmount(%q, floor %d, %d) = %d, expected %d", tt.rounding, tt.floor, tt.available, got, tt.expected)
+		}
+	}
+}
+
+// TestDecayDue verifies the decay runs once per week or month.
+func TestDecayDue(t *testing.T) {
+	now := time.Date(2026, 3, 18, 4, 0, 0, 0, time.UTC)
+	tests := []struct {
+		schedule string
+		lastRun  time.Time
+		expected bool
+	}{
+		{decayWeekly, time.Time{}, true},
+		{decayWeekly, time.Date(2026, 3, 16, 4, 0, 0, 0, time.UTC), false},
+		{decayWeekly, time.Date(2026, 3, 15, 4, 0, 0, 0, time.UTC), true},
+		{decayMonthly, time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC), false},
+		{decayMonthly, time.Date(2026, 2, 28, 4, 0, 0, 0, time.UTC), true},
+		{"", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		lastRun, _ := types.ParseDateTime(tt.lastRun)
+		settings := &Settings{DecaySchedule: tt.schedule, DecayPercentage: 10, DecayLastRun: lastRun}
+		if got := decayDue(settings, now); got != tt.expected {
+			t.Fatalf("decayDue(%q, %v) = %v, expected %v", tt.schedule, tt.lastRun, got, tt.expected)
+		}
+	}
+}
+
+// TestRunTokenDecay verifies only available tokens of non-exempt users decay, once per period.
+func TestRunTokenDecay(t *testing.T) {
+	app := newTestApp(t)
+
+	insertSettingsRecord(t, app)
+	if _, err := app.DB().Update("settings", dbx.Params{
+		"decaySchedule":    decayWeekly,
+		"decayPercentage":  10,
+		"decayFloor":       5,
+		"decayExemptRoles": `["admin"]`,
+		"decayRounding":    decayRoundUp,
+	}, nil).Execute(); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	poor := createTestUser(t, app, "poor@example.com", []string{"member"})
+	admin := createTestUser(t, app, "admin@example.com", []string{"member", "admin"})
+	setTestTokens(t, app, bidder, 100)
+	setTestTokens(t, app, poor, 5)
+	setTestTokens(t, app, admin, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, bidder, auction.Id, `{"amount":25}`)
+
+	if err := runTokenDecay(app); err != nil {
+		t.Fatalf("runTokenDecay returned error: %v", err)
+	}
+	assertTokens(t, app, bidder.Id, 92)
+	assertReservedTokens(t, app, bidder.Id, 25)
+	assertTokens(t, app, poor.Id, 5)
+	assertTokens(t, app, admin.Id, 100)
+	transactions, err := app.FindAllRecords("transactions", dbx.HashExp{"user": bidder.Id, "amount": -8})
+	if err != nil || len(transactions) != 1 {
+		t.Fatalf("expected one decay transaction, got %d: %v", len(transactions), err)
+	}
+	if note := transactions[0].GetString("note"); note != "Scheduled DKP decay: 10% (weekly)" {
+		t.Fatalf("unexpected decay note %q", note)
+	}
+
+	if err := runTokenDecay(app); err != nil {
+		t.Fatalf("runTokenDecay returned error: %v", err)
+	}
+	assertTokens(t, app, bidder.Id, 92)
+}
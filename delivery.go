@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	channelInApp   = "inApp"
+	channelDiscord = "discord"
+)
+
+// deliveryChannel is a destination outbox notifications are delivered to.
+type deliveryChannel interface {
+	// Name identifies the channel on outbox records.
+	Name() string
+	// Payload returns what the channel sends for a user notification, false when it does not reach the user.
+	Payload(settings *Settings, user *core.Record, event NotificationEvent, message string) (any, bool)
+	// Deliver sends a queued outbox record.
+	Deliver(app core.App, record *core.Record) error
+}
+
+// deliveryChannels lists the channels user notifications are fanned out to.
+var deliveryChannels = []deliveryChannel{inAppChannel{}, newDiscordChannel()}
+
+// findDeliveryChannel returns the registered channel with the given name.
+func findDeliveryChannel(name string) (deliveryChannel, error) {
+	if name == "" {
+		name = channelInApp
+	}
+	for _, channel := range deliveryChannels {
+		if channel.Name() == name {
+			return channel, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown delivery channel %q", name)
+}
+
+// inAppChannel delivers notifications to the notifications collection shown in the UI.
+type inAppChannel struct{}
+
+func (inAppChannel) Name() string {
+	return channelInApp
+}
+
+// Payload keeps the structured event so the stored notification can link to what it is about.
+func (inAppChannel) Payload(settings *Settings, user *core.Record, event NotificationEvent, message string) (any, bool) {
+	return event, true
+}
+
+func (inAppChannel) Deliver(app core.App, record *core.Record) error {
+	event := NotificationEvent{Type: record.GetString("event")}
+	if raw := record.GetString("payload"); raw != "" && raw != "null" {
+		if err := record.UnmarshalJSONField("payload", &event); err != nil {
+			return err
+		}
+	}
+	return createNotification(app, record.GetString("user"), event, record.GetString("message"))
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	auctionEventCreated   = "created"
+	auctionEventOpened    = "opened"
+	auctionEventEnding    = "ending"
+	auctionEventFinished  = "finished"
+	auctionEventCancelled = "cancelled"
+	auctionEventUnsold    = "unsold"
+)
+
+// rarityColors maps item rarities to the embed colors used by the UI labels.
+var rarityColors = map[string]int{
+	"common":   0x9ca3af,
+	"uncommon": 0x22c55e,
+	"rare":     0x3b82f6,
+	"rare_t2":  0x3b82f6,
+	"epic":     0xa855f7,
+	"epic_t2":  0xa855f7,
+	"epic_t3":  0xa855f7,
+	"heroic":   0xf59e0b,
+	"artifact": 0xef4444,
+}
+
+// discordChannel delivers notifications through the Discord webhook configured in settings.
+type discordChannel struct {
+	client *http.Client
+}
+
+func newDiscordChannel() *discordChannel {
+	return &discordChannel{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (c *discordChannel) Name() string {
+	return channelDiscord
+}
+
+// Payload mentions the user in the webhook channel when user mentions are enabled.
+func (c *discordChannel) Payload(settings *Settings, user *core.Record, event NotificationEvent, message string) (any, bool) {
+	discordId := user.GetString("discordId")
+	if !settings.EnableDiscordWebhook || !settings.DiscordMentionUsers || discordId == "" {
+		return nil, false
+	}
+	return DiscordMessage{
+		Content:         fmt.Sprintf("<@%s> %s", discordId, message),
+		AllowedMentions: &DiscordAllowedMentions{Users: []string{discordId}},
+	}, true
+}
+
+// Deliver posts the stored payload to the webhook. Messages queued before the webhook was disabled are dropped.
+func (c *discordChannel) Deliver(app core.App, record *core.Record) error {
+	settings, err := GetSettings(app)
+	if err != nil {
+		return err
+	}
+	if !settings.EnableDiscordWebhook || settings.DiscordWebhookUrl == "" {
+		app.Logger().Info("Discord webhook is disabled, dropping notification", "outboxId", record.Id)
+		return nil
+	}
+
+	resp, err := c.client.Post(settings.DiscordWebhookUrl, "application/json", bytes.NewReader([]byte(record.GetString("payload"))))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("discord webhook returned status code %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// announceAuction queues a Discord embed announcing an auction event to the whole guild.
+func announceAuction(app core.App, event string, auction *core.Record) error {
+	settings, err := GetSettings(app)
+	if err != nil {
+		return err
+	}
+	if !settings.EnableDiscordWebhook || settings.DiscordWebhookUrl == "" {
+		return nil
+	}
+	embed, err := auctionEmbed(app, event, auction)
+	if err != nil {
+		return err
+	}
+	return enqueueNotification(app, channelDiscord, "", "", embed.Title, DiscordMessage{Embeds: []DiscordEmbed{embed}})
+}
+
+// auctionEmbed builds the Discord embed describing an auction event.
+func auctionEmbed(app core.App, event string, auction *core.Record) (DiscordEmbed, error) {
+	itemName := auction.GetString("itemName")
+	rarity := auction.GetString("rarity")
+	embed := DiscordEmbed{
+		Description: auction.GetString("description"),
+		Color:       rarityColors[rarity],
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+	if image := auction.GetString("mainImage"); image != "" {
+		appUrl := strings.TrimRight(app.Settings().Meta.AppURL, "/")
+		embed.Thumbnail = &DiscordEmbedImage{Url: appUrl + "/api/files/" + auction.BaseFilesPath() + "/" + image}
+	}
+	if rarity != "" {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Rarity", Value: rarity, Inline: true})
+	}
+	if quantity := auction.GetInt("quantity"); quantity > 1 {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Quantity", Value: fmt.Sprint(quantity), Inline: true})
+	}
+	endTime := auction.GetDateTime("endTime").Time().Unix()
+
+	switch event {
+	case auctionEventCreated:
+		embed.Title = "New auction: " + itemName
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Starting bid", Value: fmt.Sprint(auction.GetInt("startingBid")), Inline: true})
+		if auction.GetString("state") == "scheduled" {
+			startTime := auction.GetDateTime("startTime").Time().Unix()
+			embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Opens", Value: fmt.Sprintf("<t:%d:R>", startTime), Inline: true})
+		}
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Ends", Value: fmt.Sprintf("<t:%d:R>", endTime), Inline: true})
+	case auctionEventOpened:
+		embed.Title = "Auction open: " + itemName
+		embed.Fields = append(embed.Fields,
+			DiscordEmbedField{Name: "Starting bid", Value: fmt.Sprint(auction.GetInt("startingBid")), Inline: true},
+			DiscordEmbedField{Name: "Ends", Value: fmt.Sprintf("<t:%d:R>", endTime), Inline: true},
+		)
+	case auctionEventEnding:
+		embed.Title = "Auction ending soon: " + itemName
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Ends", Value: fmt.Sprintf("<t:%d:R>", endTime), Inline: true})
+		if !isSealedMode(auction.GetString("mode")) {
+			embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Current bid", Value: fmt.Sprint(auction.GetInt("currentBid")), Inline: true})
+		}
+	case auctionEventFinished:
+		embed.Title = "Auction finished: " + itemName
+		winnerIds := auction.GetStringSlice("winners")
+		if winner := auction.GetString("winner"); winner != "" {
+			winnerIds = []string{winner}
+		}
+		if len(winnerIds) == 0 {
+			embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Winner", Value: "No bids"})
+			break
+		}
+		winners, err := app.FindRecordsByIds("users", winnerIds)
+		if err != nil {
+			return embed, err
+		}
+		names := make([]string, 0, len(winners))
+		for _, winner := range winners {
+			names = append(names, winner.GetString("name"))
+		}
+		bidLabel := "Winning bid"
+		if len(names) > 1 {
+			bidLabel = "Lowest winning bid"
+		}
+		embed.Fields = append(embed.Fields,
+			DiscordEmbedField{Name: "Winner", Value: strings.Join(names, ", "), Inline: true},
+			DiscordEmbedField{Name: bidLabel, Value: fmt.Sprint(auction.GetInt("currentBid")), Inline: true},
+		)
+	case auctionEventUnsold:
+		embed.Title = "Auction unsold: " + itemName
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Winner", Value: "Reserve price not met"})
+	case auctionEventCancelled:
+		embed.Title = "Auction cancelled: " + itemName
+		if reason := auction.GetString("cancelReason"); reason != "" {
+			embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Reason", Value: reason})
+		}
+	default:
+		return embed, fmt.Errorf("unknown auction event %q", event)
+	}
+	return embed, nil
+}
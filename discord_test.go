@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+)
+
+// TestDiscordAnnouncesAuction verifies auction embeds are posted to the configured webhook.
+func TestDiscordAnnouncesAuction(t *testing.T) {
+	app := newTestApp(t)
+
+	received := []DiscordMessage{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		message := DiscordMessage{}
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Errorf("invalid webhook body: %v", err)
+		}
+		received = append(received, message)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	enableTestDiscordWebhook(t, app, server.URL)
+
+	auction := createTestAuction(t, app, 10)
+	auction.Set("rarity", "epic")
+	if err := announceAuction(app.App, auctionEventCreated, auction); err != nil {
+		t.Fatalf("announceAuction returned error: %v", err)
+	}
+	if err := deliverPendingNotifications(app.App); err != nil {
+		t.Fatalf("deliverPendingNotifications returned error: %v", err)
+	}
+
+	if len(received) != 1 || len(received[0].Embeds) != 1 {
+		t.Fatalf("expected a single embed to be posted, got %v", received)
+	}
+	embed := received[0].Embeds[0]
+	if embed.Title != "New auction: Test item" || embed.Color != rarityColors["epic"] {
+		t.Fatalf("unexpected embed: %#v", embed)
+	}
+	if embed.Fields[0] != (DiscordEmbedField{Name: "Rarity", Value: "epic", Inline: true}) {
+		t.Fatalf("expected rarity field first, got %#v", embed.Fields)
+	}
+}
+
+// TestDiscordDeliveryFailureIsRetried verifies webhook errors keep the notification pending.
+func TestDiscordDeliveryFailureIsRetried(t *testing.T) {
+	app := newTestApp(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	enableTestDiscordWebhook(t, app, server.URL)
+
+	user := createTestUser(t, app, "raider@example.com", []string{"member"})
+	user.Set("discordId", "1234")
+	if err := app.Save(user); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+	if err := notifyUser(app.App, user.Id, NotificationEvent{Type: eventWon}); err != nil {
+		t.Fatalf("notifyUser returned error: %v", err)
+	}
+	if err := deliverPendingNotifications(app.App); err != nil {
+		t.Fatalf("deliverPendingNotifications returned error: %v", err)
+	}
+
+	outbox, err := app.FindFirstRecordByData("notificationOutbox", "channel", channelDiscord)
+	if err != nil {
+		t.Fatalf("failed to find discord outbox record: %v", err)
+	}
+	if outbox.GetString("state") != outboxPending || outbox.GetInt("attempts") != 1 {
+		t.Fatalf("expected discord delivery to be retried, got state %q", outbox.GetString("state"))
+	}
+	inApp, err := app.FindFirstRecordByData("notificationOutbox", "channel", channelInApp)
+	if err != nil {
+		t.Fatalf("failed to find in-app outbox record: %v", err)
+	}
+	if inApp.GetString("state") != outboxDelivered {
+		t.Fatalf("expected in-app delivery to succeed, got state %q", inApp.GetString("state"))
+	}
+}
+
+// enableTestDiscordWebhook points the Discord webhook settings at a test server.
+func enableTestDiscordWebhook(t *testing.T, app *pocketbase.PocketBase, url string) {
+	t.Helper()
+
+	insertSettingsRecord(t, app)
+	if _, err := app.DB().Update("settings", dbx.Params{
+		"enableDiscordWebhook": true,
+		"discordWebhookUrl":    url,
+		"discordMentionUsers":  true,
+	}, nil).Execute(); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+}
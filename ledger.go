@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+)
+
+const (
+	ledgerWallet = "wallet"
+	ledgerEscrow = "escrow"
+	ledgerPool   = "pool"
+)
+
+// ledgerEntry is one side of a token movement. Entries of a journal always sum to zero.
+type ledgerEntry struct {
+	Account   string
+	UserId    string
+	AuctionId string
+	Amount    int
+}
+
+// postJournal records a balanced set of ledger entries and refreshes the cached balances of the users involved.
+func postJournal(app core.App, transactionId string, note string, entries ...ledgerEntry) error {
+	sum := 0
+	for _, entry := range entries {
+		sum += entry.Amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("unbalanced journal %q: entries sum to %d", note, sum)
+	}
+	coll, err := app.FindCachedCollectionByNameOrId("ledgerEntries")
+	if err != nil {
+		return err
+	}
+
+	journal := security.RandomStringWithAlphabet(15, "abcdefghijklmnopqrstuvwxyz0123456789")
+	userIds := []string{}
+	for _, entry := range entries {
+		if entry.Amount == 0 {
+			continue
+		}
+		record := core.NewRecord(coll)
+		record.Set("journal", journal)
+		record.Set("account", entry.Account)
+		record.Set("user", entry.UserId)
+		record.Set("auction", entry.AuctionId)
+		record.Set("amount", entry.Amount)
+		record.Set("transaction", transactionId)
+		record.Set("note", note)
+		if err := app.Save(record); err != nil {
+			return err
+		}
+		if entry.UserId != "" {
+			userIds = append(userIds, entry.UserId)
+		}
+	}
+	for _, userId := range userIds {
+		if err := refreshUserBalance(app, userId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transferTokens moves tokens between the guild pool and a user's wallet, negative amounts going back to the pool.
+// The movement is listed in the user's transactions.
+func transferTokens(app core.App, userId string, amount int, note string, authorId string) error {
+	transaction, err := createTransactionRecord(app, userId, amount, note, authorId)
+	if err != nil {
+		return err
+	}
+	return postJournal(app, transaction.Id, note,
+		ledgerEntry{Account: ledgerPool, Amount: -amount},
+		ledgerEntry{Account: ledgerWallet, UserId: userId, Amount: amount},
+	)
+}
+
+// moveToEscrow holds tokens of a user's wallet for an auction, negative amounts releasing them.
+func moveToEscrow(app core.App, auctionId string, userId string, amount int) error {
+	return postJournal(app, "", "Escrow for auction",
+		ledgerEntry{Account: ledgerWallet, UserId: userId, Amount: -amount},
+		ledgerEntry{Account: ledgerEscrow, UserId: userId, AuctionId: auctionId, Amount: amount},
+	)
+}
+
+// ledgerBalance sums the entries of an account matching the given conditions.
+func ledgerBalance(app core.App, account string, where dbx.HashExp) (int, error) {
+	var balance struct {
+		Amount int `db:"amount"`
+	}
+	err := app.DB().
+		Select("COALESCE(SUM(amount), 0) as amount").
+		From("ledgerEntries").
+		Where(dbx.And(dbx.HashExp{"account": account}, where)).
+		One(&balance)
+	return balance.Amount, err
+}
+
+// escrowBalance returns the tokens a user holds in escrow for an auction.
+func escrowBalance(app core.App, auctionId string, userId string) (int, error) {
+	return ledgerBalance(app, ledgerEscrow, dbx.HashExp{"auction": auctionId, "user": userId})
+}
+
+// escrowHolders returns the users holding tokens in escrow for an auction with their balances.
+func escrowHolders(app core.App, auctionId string) (map[string]int, error) {
+	rows := []struct {
+		User   string `db:"user"`
+		Amount int    `db:"amount"`
+	}{}
+	err := app.DB().
+		Select("user", "SUM(amount) as amount").
+		From("ledgerEntries").
+		Where(dbx.HashExp{"account": ledgerEscrow, "auction": auctionId}).
+		GroupBy("user").
+		Having(dbx.NewExp("SUM(amount) != 0")).
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+	holders := make(map[string]int, len(rows))
+	for _, row := range rows {
+		holders[row.User] = row.Amount
+	}
+	return holders, nil
+}
+
+// escrowHolding is the balance a user holds in escrow for one auction.
+type escrowHolding struct {
+	User    string `db:"user"`
+	Auction string `db:"auction"`
+	Amount  int    `db:"amount"`
+}
+
+// findEscrowHoldings returns every non-zero escrow balance, per user and auction.
+func findEscrowHoldings(app core.App) ([]escrowHolding, error) {
+	holdings := []escrowHolding{}
+	err := app.DB().
+		Select("user", "auction", "SUM(amount) as amount").
+		From("ledgerEntries").
+		Where(dbx.HashExp{"account": ledgerEscrow}).
+		GroupBy("user", "auction").
+		Having(dbx.NewExp("SUM(amount) != 0")).
+		All(&holdings)
+	return holdings, err
+}
+
+// refreshUserBalance caches the ledger balances of a user on the user record.
+// tokens counts everything the user owns, reservedTokens the part of it held in escrow.
+func refreshUserBalance(app core.App, userId string) error {
+	wallet, err := ledgerBalance(app, ledgerWallet, dbx.HashExp{"user": userId})
+	if err != nil {
+		return err
+	}
+	escrow, err := ledgerBalance(app, ledgerEscrow, dbx.HashExp{"user": userId})
+	if err != nil {
+		return err
+	}
+	user, err := app.FindRecordById("users", userId)
+	if err != nil {
+		return err
+	}
+	if user.GetInt("tokens") == wallet+escrow && user.GetInt("reservedTokens") == escrow {
+		return nil
+	}
+	user.Set("tokens", wallet+escrow)
+	user.Set("reservedTokens", escrow)
+	return app.Save(user)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pocketbase/dbx"
+)
+
+// TestLedgerKeepsBalancesInSync verifies every movement is balanced and the cached user balances follow the ledger.
+func TestLedgerKeepsBalancesInSync(t *testing.T) {
+	app := newTestApp(t)
+
+	user := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	auction := createTestAuction(t, app, 10)
+
+	if err := transferTokens(app, user.Id, 100, "Raid attendance", ""); err != nil {
+		t.Fatalf("transferTokens returned error: %v", err)
+	}
+	if err := setReservation(app, auction.Id, user.Id, 40); err != nil {
+		t.Fatalf("setReservation returned error: %v", err)
+	}
+	assertTokens(t, app, user.Id, 100)
+	assertReservedTokens(t, app, user.Id, 40)
+
+	if err := releaseReservations(app, auction.Id); err != nil {
+		t.Fatalf("releaseReservations returned error: %v", err)
+	}
+	if _, err := awardAuction(app, auction.Id, user.Id, 40, []string{user.Id}); err != nil {
+		t.Fatalf("awardAuction returned error: %v", err)
+	}
+	assertTokens(t, app, user.Id, 60)
+	assertReservedTokens(t, app, user.Id, 0)
+
+	total, err := ledgerBalance(app, ledgerPool, dbx.HashExp{})
+	if err != nil {
+		t.Fatalf("ledgerBalance returned error: %v", err)
+	}
+	if total != -60 {
+		t.Fatalf("expected the pool to have paid out 60 tokens, got %d", -total)
+	}
+	var unbalanced []string
+	if err := app.DB().
+		Select("journal").
+		From("ledgerEntries").
+		GroupBy("journal").
+		Having(dbx.NewExp("SUM(amount) != 0")).
+		Column(&unbalanced); err != nil {
+		t.Fatalf("failed to check journals: %v", err)
+	}
+	if len(unbalanced) != 0 {
+		t.Fatalf("expected every journal to balance, got %v", unbalanced)
+	}
+}
+
+// TestPostJournalRejectsUnbalancedEntries verifies entries that do not sum to zero are refused.
+func TestPostJournalRejectsUnbalancedEntries(t *testing.T) {
+	app := newTestApp(t)
+
+	user := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	err := postJournal(app, "", "Broken movement",
+		ledgerEntry{Account: ledgerPool, Amount: -10},
+		ledgerEntry{Account: ledgerWallet, UserId: user.Id, Amount: 20},
+	)
+	if err == nil {
+		t.Fatal("expected an unbalanced journal to be rejected")
+	}
+	assertTokens(t, app, user.Id, 0)
+}
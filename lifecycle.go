@@ -0,0 +1,419 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	auctionLogClosed   = "closed"
+	auctionLogExtended = "extended"
+	auctionLogPaused   = "paused"
+	auctionLogResumed  = "resumed"
+
+	auctionLogBidRetracted = "bidRetracted"
+)
+
+// scheduledState returns the state a published auction starts in, scheduled while its start time is ahead.
+func scheduledState(startTime types.DateTime) string {
+	if !startTime.IsZero() && startTime.After(types.NowDateTime()) {
+		return "scheduled"
+	}
+	return "ongoing"
+}
+
+// openScheduledAuctions opens every scheduled auction whose start time has passed.
+func openScheduledAuctions(app core.App) error {
+	return app.RunInTransaction(func(tx core.App) error {
+		records, err := tx.FindRecordsByFilter("auctions", "state = 'scheduled' && startTime <= @now", "startTime", 0, 0, nil)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if _, err := claimAuctionOpen(tx, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// claimAuctionOpen takes a scheduled auction out of its state with a conditional update before opening it,
+// so an auction cancelled in the meantime or opened by an overlapping run is left alone.
+func claimAuctionOpen(app core.App, auction *core.Record) (bool, error) {
+	res, err := app.DB().Update(
+		"auctions",
+		dbx.Params{"state": "ongoing"},
+		dbx.HashExp{"id": auction.Id, "state": "scheduled"},
+	).Execute()
+	if err != nil {
+		return false, err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+	return true, openAuction(app, auction)
+}
+
+// openAuction starts accepting bids on a draft or scheduled auction and tells the guild about it.
+func openAuction(app core.App, auction *core.Record) error {
+	auction.Set("state", "ongoing")
+	if err := app.Save(auction); err != nil {
+		return err
+	}
+	if err := announceAuction(app, auctionEventOpened, auction); err != nil {
+		return err
+	}
+	for _, userId := range auction.GetStringSlice("favourites") {
+		if err := notifyUser(app, userId, NotificationEvent{
+			Type:    eventAuctionOpened,
+			Auction: auction.Id,
+			Data:    map[string]any{"itemName": auction.GetString("itemName")},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancelAuction withdraws an auction that has not finished, returning every reserved token to its bidders.
+func cancelAuction(app core.App, auction *core.Record, reason string, cancelledBy string) error {
+	auction.Set("state", "cancelled")
+	auction.Set("cancelReason", reason)
+	auction.Set("cancelledBy", cancelledBy)
+	if err := app.Save(auction); err != nil {
+		return err
+	}
+	if err := releaseReservations(app, auction.Id); err != nil {
+		return err
+	}
+	bidderIds, err := findBidderIds(app, auction.Id)
+	if err != nil {
+		return err
+	}
+	for _, userId := range bidderIds {
+		if err := notifyUser(app, userId, NotificationEvent{
+			Type:    eventAuctionCancelled,
+			Auction: auction.Id,
+			Actor:   cancelledBy,
+			Data:    map[string]any{"itemName": auction.GetString("itemName"), "reason": reason},
+		}); err != nil {
+			return err
+		}
+	}
+	return announceAuction(app, auctionEventCancelled, auction)
+}
+
+// relistSkippedFields are the auction fields describing how a listing went rather than the item, which a relist starts over.
+var relistSkippedFields = []string{
+	"id", "mainImage", "currentBid", "startTime", "endTime", "state", "winner", "winners", "favourites",
+	"endingAnnounced", "cancelReason", "cancelledBy", "relistedAs", "pausedAt", "antiSnipeExtension",
+}
+
+// relistAuction creates a fresh ongoing copy of an unsold auction running as long as the original did.
+// Every field describing the item and its rules is copied.
+func relistAuction(app core.App, auction *core.Record) (*core.Record, error) {
+	start := auction.GetDateTime("startTime")
+	if start.IsZero() {
+		start = auction.GetDateTime("created")
+	}
+	duration := auction.GetDateTime("endTime").Time().Sub(start.Time())
+	if duration <= 0 {
+		duration = 24 * time.Hour
+	}
+
+	relisted := core.NewRecord(auction.Collection())
+	for _, field := range auction.Collection().Fields {
+		if field.Type() == core.FieldTypeAutodate || slices.Contains(relistSkippedFields, field.GetName()) {
+			continue
+		}
+		relisted.Set(field.GetName(), auction.Get(field.GetName()))
+	}
+	relisted.Set("currentBid", 0)
+	relisted.Set("state", "ongoing")
+	relisted.Set("endTime", types.NowDateTime().Add(duration))
+	if image := auction.GetString("mainImage"); image != "" {
+		file, err := copyRecordFile(app, auction, image)
+		if err != nil {
+			return nil, err
+		}
+		relisted.Set("mainImage", file)
+	}
+	if err := app.Save(relisted); err != nil {
+		return nil, err
+	}
+	auction.Set("relistedAs", relisted.Id)
+	if err := app.Save(auction); err != nil {
+		return nil, err
+	}
+	return relisted, nil
+}
+
+// copyRecordFile loads a file stored on a record so it can be attached to another record.
+func copyRecordFile(app core.App, record *core.Record, name string) (*filesystem.File, error) {
+	fsys, err := app.NewFilesystem()
+	if err != nil {
+		return nil, err
+	}
+	defer fsys.Close()
+	r, err := fsys.GetReader(record.BaseFilesPath() + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	fileData, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.NewFileFromBytes(fileData, name)
+}
+
+// voidAuctionResult reverses a won auction result, refunding the winner through a compensating transaction.
+// Zero-sum shares paid out for the win are taken back as well.
+func voidAuctionResult(app core.App, result *core.Record, reason string, voidedBy string) error {
+	winnerId := result.GetString("winner")
+	amount := result.GetInt("amount")
+	if err := transferTokens(app, winnerId, amount, "Refund for voided auction win. Reason: "+reason, voidedBy); err != nil {
+		return err
+	}
+	if err := reverseZeroSum(app, result, reason, voidedBy); err != nil {
+		return err
+	}
+
+	result.Set("voided", true)
+	result.Set("voidReason", reason)
+	result.Set("voidedBy", voidedBy)
+	if err := app.Save(result); err != nil {
+		return err
+	}
+
+	auction, err := app.FindRecordById("auctions", result.GetString("auction"))
+	if err != nil {
+		return err
+	}
+	if auction.GetString("winner") == winnerId {
+		auction.Set("winner", "")
+	}
+	auction.Set("winners", slices.DeleteFunc(auction.GetStringSlice("winners"), func(id string) bool {
+		return id == winnerId
+	}))
+	if err := app.Save(auction); err != nil {
+		return err
+	}
+	return notifyUser(app, winnerId, NotificationEvent{
+		Type:    eventResultVoided,
+		Auction: auction.Id,
+		Amount:  amount,
+		Actor:   voidedBy,
+		Data:    map[string]any{"itemName": auction.GetString("itemName"), "reason": reason},
+	})
+}
+
+// findRunnerUp returns the highest bid on an auction from a user who never held a result for it, nil when nobody is left.
+func findRunnerUp(app core.App, auctionId string) (*rankedBid, error) {
+	ranked, err := findRankedBids(app, auctionId)
+	if err != nil {
+		return nil, err
+	}
+	results, err := app.FindRecordsByFilter("auctionsResult", "auction = {:auctionId} && winner != ''", "", 0, 0, dbx.Params{"auctionId": auctionId})
+	if err != nil {
+		return nil, err
+	}
+	awarded := map[string]bool{}
+	for _, result := range results {
+		awarded[result.GetString("winner")] = true
+	}
+	for _, bid := range ranked {
+		if !awarded[bid.UserId] {
+			return &bid, nil
+		}
+	}
+	return nil, nil
+}
+
+// reassignAuctionResult awards a voided result to the runner-up at their own bid amount, as long as it meets the reserve price.
+func reassignAuctionResult(app core.App, voided *core.Record) (*core.Record, error) {
+	auction, err := app.FindRecordById("auctions", voided.GetString("auction"))
+	if err != nil {
+		return nil, err
+	}
+	runnerUp, err := findRunnerUp(app, auction.Id)
+	if err != nil {
+		return nil, err
+	}
+	if runnerUp == nil {
+		return nil, fmt.Errorf("auction has no other bidder")
+	}
+	if reservePrice := auction.GetInt("reservePrice"); runnerUp.Amount < reservePrice {
+		return nil, fmt.Errorf("runner-up bid %d is below the reserve price", runnerUp.Amount)
+	}
+	user, err := app.FindRecordById("users", runnerUp.UserId)
+	if err != nil {
+		return nil, err
+	}
+	available, err := availableTokens(app, user, auction.Id)
+	if err != nil {
+		return nil, err
+	}
+	if available < runnerUp.Amount {
+		return nil, fmt.Errorf("runner-up has %d available tokens but bid %d", available, runnerUp.Amount)
+	}
+
+	// winners of the other units get no share of this one either
+	winnerIds := []string{runnerUp.UserId}
+	if auction.GetInt("quantity") > 1 {
+		winnerIds = append(auction.GetStringSlice("winners"), runnerUp.UserId)
+	}
+	result, err := awardAuction(app, auction.Id, runnerUp.UserId, runnerUp.Amount, winnerIds)
+	if err != nil {
+		return nil, err
+	}
+	result.Set("replaces", voided.Id)
+	if err := app.Save(result); err != nil {
+		return nil, err
+	}
+	if auction.GetInt("quantity") > 1 {
+		auction.Set("winners", append(auction.GetStringSlice("winners"), runnerUp.UserId))
+		auction.Set("currentBid", min(auction.GetInt("currentBid"), runnerUp.Amount))
+	} else {
+		auction.Set("winner", runnerUp.UserId)
+		auction.Set("currentBid", runnerUp.Amount)
+	}
+	if err := app.Save(auction); err != nil {
+		return nil, err
+	}
+	if err := notifyUser(app, runnerUp.UserId, NotificationEvent{
+		Type:    eventWon,
+		Auction: auction.Id,
+		Amount:  runnerUp.Amount,
+		Data:    map[string]any{"itemName": auction.GetString("itemName")},
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// logAuctionEvent appends a manager action to the auction event log.
+func logAuctionEvent(app core.App, auctionId string, eventType string, actorId string, data map[string]any) (*core.Record, error) {
+	coll, err := app.FindCachedCollectionByNameOrId("auctionEvents")
+	if err != nil {
+		return nil, err
+	}
+	record := core.NewRecord(coll)
+	record.Set("auction", auctionId)
+	record.Set("type", eventType)
+	record.Set("actor", actorId)
+	if data != nil {
+		record.Set("data", data)
+	}
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// broadcastAuctionEvent sends a logged auction event to the managers subscribed to the auction event log,
+// which like the auctionEvents collection is kept from everybody else.
+func broadcastAuctionEvent(app core.App, event *core.Record) error {
+	return notifyClients(app, "auction_events", AuctionEventMessage{
+		Id:      event.Id,
+		Auction: event.GetString("auction"),
+		Type:    event.GetString("type"),
+		Actor:   event.GetString("actor"),
+	}, isManagerClient)
+}
+
+// auctionStreamFields lists the auction fields live bidding views follow.
+var auctionStreamFields = []string{"currentBid", "winner", "winners", "endTime", "state"}
+
+// auctionTopic returns the realtime topic carrying the live updates of an auction.
+func auctionTopic(auctionId string) string {
+	return "auction/" + auctionId
+}
+
+// auctionStreamChanged reports whether a saved auction changed anything live bidding views show.
+func auctionStreamChanged(auction *core.Record) bool {
+	original := auction.Original()
+	for _, field := range auctionStreamFields {
+		if fmt.Sprint(original.Get(field)) != fmt.Sprint(auction.Get(field)) {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcastAuctionUpdate pushes the leading bid, end time and state of an auction to its topic.
+// Only validated users and managers get it. Members get no bids of a sealed auction until it has a result,
+// and neither the names nor the ids of anonymous bidders.
+func broadcastAuctionUpdate(app core.App, auction *core.Record) error {
+	message := AuctionUpdateMessage{
+		Auction:    auction.Id,
+		State:      auction.GetString("state"),
+		EndTime:    auction.GetDateTime("endTime").String(),
+		CurrentBid: auction.GetInt("currentBid"),
+		Winner:     auction.GetString("winner"),
+		Winners:    auction.GetStringSlice("winners"),
+	}
+	settings, err := GetSettings(app)
+	if err != nil {
+		return err
+	}
+	if !settings.AnonymousBidders {
+		winnerIds := message.Winners
+		if message.Winner != "" {
+			winnerIds = []string{message.Winner}
+		}
+		winners, err := app.FindRecordsByIds("users", winnerIds)
+		if err != nil {
+			return err
+		}
+		names := map[string]string{}
+		for _, winner := range winners {
+			names[winner.Id] = winner.GetString("name")
+		}
+		for _, winnerId := range winnerIds {
+			message.WinnerNames = append(message.WinnerNames, names[winnerId])
+		}
+	}
+	if err := notifyClients(app, auctionTopic(auction.Id), message, isManagerClient); err != nil {
+		return err
+	}
+
+	if isSealedMode(auction.GetString("mode")) && message.State != "finished" && message.State != "unsold" {
+		message.CurrentBid = 0
+		message.Winner, message.Winners, message.WinnerNames = "", []string{}, nil
+	} else if settings.AnonymousBidders {
+		message.Winner, message.Winners = "", []string{}
+	}
+	return notifyClients(app, auctionTopic(auction.Id), message, isMemberClient)
+}
+
+// extendAuction moves the end of an ongoing or paused auction later by the given minutes.
+func extendAuction(app core.App, auction *core.Record, minutes int) error {
+	auction.Set("endTime", auction.GetDateTime("endTime").Add(time.Duration(minutes)*time.Minute))
+	auction.Set("endingAnnounced", false)
+	return app.Save(auction)
+}
+
+// pauseAuction stops an ongoing auction from taking bids and from running out.
+func pauseAuction(app core.App, auction *core.Record) error {
+	auction.Set("state", "paused")
+	auction.Set("pausedAt", types.NowDateTime())
+	return app.Save(auction)
+}
+
+// resumeAuction reopens a paused auction, pushing its end back by the time it spent paused.
+func resumeAuction(app core.App, auction *core.Record) (time.Duration, error) {
+	paused := time.Since(auction.GetDateTime("pausedAt").Time()).Round(time.Second)
+	auction.Set("state", "ongoing")
+	auction.Set("pausedAt", nil)
+	auction.Set("endTime", auction.GetDateTime("endTime").Add(paused))
+	return paused, app.Save(auction)
+}
@@ -0,0 +1,603 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/subscriptions"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// TestOpenScheduledAuctions verifies only auctions whose start time has passed are opened.
+func TestOpenScheduledAuctions(t *testing.T) {
+	app := newTestApp(t)
+
+	member := createTestUser(t, app, "member@example.com", []string{"member"})
+	due := createTestAuction(t, app, 10)
+	due.Set("state", "scheduled")
+	due.Set("startTime", time.Now().UTC().Add(-time.Minute))
+	due.Set("favourites", []string{member.Id})
+	if err := app.Save(due); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+	later := createTestAuction(t, app, 10)
+	later.Set("state", "scheduled")
+	later.Set("startTime", time.Now().UTC().Add(30*time.Minute))
+	if err := app.Save(later); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+
+	if err := openScheduledAuctions(app.App); err != nil {
+		t.Fatalf("openScheduledAuctions returned error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		id       string
+		expected string
+	}{{due.Id, "ongoing"}, {later.Id, "scheduled"}} {
+		auction, err := app.FindRecordById("auctions", tc.id)
+		if err != nil {
+			t.Fatalf("failed to find auction: %v", err)
+		}
+		if auction.GetString("state") != tc.expected {
+			t.Fatalf("expected auction state %q, got %q", tc.expected, auction.GetString("state"))
+		}
+	}
+	assertOutboxState(t, app.App, member.Id, eventAuctionOpened, outboxPending)
+}
+
+// TestClaimAuctionOpenSkipsCancelled ensures an auction cancelled after it was loaded is not opened.
+func TestClaimAuctionOpenSkipsCancelled(t *testing.T) {
+	app := newTestApp(t)
+
+	member := createTestUser(t, app, "member@example.com", []string{"member"})
+	auction := createTestAuction(t, app, 10)
+	auction.Set("state", "scheduled")
+	auction.Set("startTime", time.Now().UTC().Add(-time.Minute))
+	auction.Set("favourites", []string{member.Id})
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+	stale, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if err := cancelAuction(app, auction, "Raid cancelled", ""); err != nil {
+		t.Fatalf("cancelAuction returned error: %v", err)
+	}
+
+	opened, err := claimAuctionOpen(app, stale)
+	if err != nil {
+		t.Fatalf("claimAuctionOpen returned error: %v", err)
+	}
+	if opened {
+		t.Fatal("expected a cancelled auction not to be opened")
+	}
+	auction, err = app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("state") != "cancelled" {
+		t.Fatalf("expected the auction to stay cancelled, got %q", auction.GetString("state"))
+	}
+}
+
+// TestHandleBidRejectsScheduledAuction ensures bids wait for the auction to open.
+func TestHandleBidRejectsScheduledAuction(t *testing.T) {
+	app := newTestApp(t)
+
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	setTestTokens(t, app, bidder, 100)
+	auction := createTestAuction(t, app, 10)
+	auction.Set("state", "scheduled")
+	auction.Set("startTime", time.Now().UTC().Add(30*time.Minute))
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+
+	e, _ := newTestRequestEvent(app, bidder, `{"amount": 10}`, map[string]string{"id": auction.Id})
+	if err := handleBid(e); err == nil {
+		t.Fatalf("expected bid on a scheduled auction to be rejected")
+	}
+	assertReservedTokens(t, app, bidder.Id, 0)
+}
+
+// TestScheduledState verifies only a future start time schedules an auction.
+func TestScheduledState(t *testing.T) {
+	if got := scheduledState(types.DateTime{}); got != "ongoing" {
+		t.Fatalf("expected auction without start time to be ongoing, got %q", got)
+	}
+	if got := scheduledState(types.NowDateTime().Add(time.Hour)); got != "scheduled" {
+		t.Fatalf("expected auction starting later to be scheduled, got %q", got)
+	}
+	if got := scheduledState(types.NowDateTime().Add(-time.Hour)); got != "ongoing" {
+		t.Fatalf("expected auction that already started to be ongoing, got %q", got)
+	}
+}
+
+// TestCancelAuctionReleasesReservations verifies cancelling refunds the held tokens and tells every bidder.
+func TestCancelAuctionReleasesReservations(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	first := createTestUser(t, app, "first@example.com", []string{"member"})
+	second := createTestUser(t, app, "second@example.com", []string{"member"})
+	setTestTokens(t, app, first, 100)
+	setTestTokens(t, app, second, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, first, auction.Id, `{"amount": 10}`)
+	placeTestBid(t, app, second, auction.Id, `{"amount": 20}`)
+
+	e, _ := newTestRequestEvent(app, manager, `{"reason": "Created by mistake"}`, map[string]string{"id": auction.Id})
+	if err := handleCancelAuction(e); err != nil {
+		t.Fatalf("handleCancelAuction returned error: %v", err)
+	}
+
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to find auction: %v", err)
+	}
+	if auction.GetString("state") != "cancelled" || auction.GetString("cancelReason") != "Created by mistake" {
+		t.Fatalf("expected a cancelled auction with its reason, got state %q", auction.GetString("state"))
+	}
+	assertReservedTokens(t, app, first.Id, 0)
+	assertReservedTokens(t, app, second.Id, 0)
+	assertTokens(t, app, second.Id, 100)
+	assertOutboxState(t, app.App, first.Id, eventAuctionCancelled, outboxPending)
+	assertOutboxState(t, app.App, second.Id, eventAuctionCancelled, outboxPending)
+}
+
+// TestCancelFinishedAuctionIsRejected ensures closed auctions keep their outcome, finished ones go through the refund flow instead.
+func TestCancelFinishedAuctionIsRejected(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	for _, state := range []string{"finished", "unsold", "cancelled"} {
+		auction := createTestAuction(t, app, 10)
+		auction.Set("state", state)
+		if err := app.Save(auction); err != nil {
+			t.Fatalf("failed to save auction: %v", err)
+		}
+
+		e, _ := newTestRequestEvent(app, manager, `{"reason": "Too late"}`, map[string]string{"id": auction.Id})
+		if err := handleCancelAuction(e); err == nil {
+			t.Fatalf("expected cancelling a %s auction to fail", state)
+		}
+		auction, err := app.FindRecordById("auctions", auction.Id)
+		if err != nil {
+			t.Fatalf("failed to reload auction: %v", err)
+		}
+		if auction.GetString("state") != state {
+			t.Fatalf("expected the auction to stay %s, got %q", state, auction.GetString("state"))
+		}
+	}
+}
+
+// TestVoidAuctionReassignsRunnerUp verifies a voided win is refunded and the runner-up pays their own bid.
+func TestVoidAuctionReassignsRunnerUp(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	winner := createTestUser(t, app, "winner@example.com", []string{"member"})
+	runnerUp := createTestUser(t, app, "runnerup@example.com", []string{"member"})
+	setTestTokens(t, app, winner, 100)
+	setTestTokens(t, app, runnerUp, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, runnerUp, auction.Id, `{"amount":20}`)
+	placeTestBid(t, app, winner, auction.Id, `{"amount":40}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	expireTestAuction(t, app, auction)
+	if err := finishAuction(app); err != nil {
+		t.Fatalf("finishAuction returned error: %v", err)
+	}
+	assertTokens(t, app, winner.Id, 60)
+
+	result, err := app.FindFirstRecordByData("auctionsResult", "winner", winner.Id)
+	if err != nil {
+		t.Fatalf("failed to find auction result: %v", err)
+	}
+	e, _ := newTestRequestEvent(app, manager, `{"reason":"Left the guild","reassign":true}`, map[string]string{"id": result.Id})
+	if err := voidAuction(e); err != nil {
+		t.Fatalf("voidAuction returned error: %v", err)
+	}
+
+	assertTokens(t, app, winner.Id, 100)
+	assertTokens(t, app, runnerUp.Id, 80)
+	result, err = app.FindRecordById("auctionsResult", result.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction result: %v", err)
+	}
+	if !result.GetBool("voided") {
+		t.Fatalf("expected auction result to be voided")
+	}
+	reassigned, err := app.FindFirstRecordByData("auctionsResult", "winner", runnerUp.Id)
+	if err != nil {
+		t.Fatalf("failed to find reassigned result: %v", err)
+	}
+	if reassigned.GetInt("amount") != 20 || reassigned.GetString("replaces") != result.Id {
+		t.Fatalf("expected runner-up to win at 20 replacing the voided result, got %d", reassigned.GetInt("amount"))
+	}
+	auction, err = app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("winner") != runnerUp.Id {
+		t.Fatalf("expected runner-up to be the auction winner, got %q", auction.GetString("winner"))
+	}
+}
+
+// TestVoidAuctionChecksRunnerUpBalance ensures the item is not reassigned to a bidder who can no longer pay.
+func TestVoidAuctionChecksRunnerUpBalance(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	winner := createTestUser(t, app, "winner@example.com", []string{"member"})
+	runnerUp := createTestUser(t, app, "runnerup@example.com", []string{"member"})
+	setTestTokens(t, app, winner, 100)
+	setTestTokens(t, app, runnerUp, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, runnerUp, auction.Id, `{"amount":20}`)
+	placeTestBid(t, app, winner, auction.Id, `{"amount":40}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	expireTestAuction(t, app, auction)
+	if err := finishAuction(app); err != nil {
+		t.Fatalf("finishAuction returned error: %v", err)
+	}
+	setTestTokens(t, app, runnerUp, 5)
+
+	result, err := app.FindFirstRecordByData("auctionsResult", "winner", winner.Id)
+	if err != nil {
+		t.Fatalf("failed to find auction result: %v", err)
+	}
+	e, _ := newTestRequestEvent(app, manager, `{"reason":"Left the guild","reassign":true}`, map[string]string{"id": result.Id})
+	if err := voidAuction(e); err == nil {
+		t.Fatalf("expected reassigning to a runner-up without tokens to fail")
+	}
+	assertTokens(t, app, winner.Id, 60)
+	assertTokens(t, app, runnerUp.Id, 5)
+}
+
+// TestVoidAuctionChecksRunnerUpReservePrice ensures the item is not reassigned below the reserve price.
+func TestVoidAuctionChecksRunnerUpReservePrice(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	winner := createTestUser(t, app, "winner@example.com", []string{"member"})
+	runnerUp := createTestUser(t, app, "runnerup@example.com", []string{"member"})
+	setTestTokens(t, app, winner, 100)
+	setTestTokens(t, app, runnerUp, 100)
+	auction := createTestAuction(t, app, 10)
+	auction.Set("reservePrice", 30)
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+	placeTestBid(t, app, runnerUp, auction.Id, `{"amount":20}`)
+	placeTestBid(t, app, winner, auction.Id, `{"amount":40}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	expireTestAuction(t, app, auction)
+	if err := finishAuction(app); err != nil {
+		t.Fatalf("finishAuction returned error: %v", err)
+	}
+
+	result, err := app.FindFirstRecordByData("auctionsResult", "winner", winner.Id)
+	if err != nil {
+		t.Fatalf("failed to find auction result: %v", err)
+	}
+	e, _ := newTestRequestEvent(app, manager, `{"reason":"Left the guild","reassign":true}`, map[string]string{"id": result.Id})
+	if err := voidAuction(e); err == nil {
+		t.Fatalf("expected reassigning below the reserve price to fail")
+	}
+	assertTokens(t, app, winner.Id, 60)
+	assertTokens(t, app, runnerUp.Id, 100)
+}
+
+// TestFinishAuctionBelowReservePrice verifies an auction missing its reserve closes unsold without charging anybody.
+func TestFinishAuctionBelowReservePrice(t *testing.T) {
+	app := newTestApp(t)
+
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	setTestTokens(t, app, bidder, 100)
+	auction := createTestAuction(t, app, 10)
+	auction.Set("reservePrice", 50)
+	auction.Set("buyoutPrice", 200)
+	auction.Set("antiSnipe", "enabled")
+	auction.Set("antiSnipeMinutes", 5)
+	auction.Set("antiSnipeTriggerMinutes", 2)
+	auction.Set("antiSnipeCapMinutes", 30)
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+	placeTestBid(t, app, bidder, auction.Id, `{"amount":30}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	expireTestAuction(t, app, auction)
+	if err := finishAuction(app); err != nil {
+		t.Fatalf("finishAuction returned error: %v", err)
+	}
+
+	auction, err = app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("state") != "unsold" || auction.GetString("winner") != "" {
+		t.Fatalf("expected an unsold auction without winner, got state %q winner %q", auction.GetString("state"), auction.GetString("winner"))
+	}
+	assertTokens(t, app, bidder.Id, 100)
+	assertReservedTokens(t, app, bidder.Id, 0)
+	result, err := app.FindFirstRecordByData("auctionsResult", "auction", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to find auction result: %v", err)
+	}
+	if !result.GetBool("noSale") || result.GetString("winner") != "" {
+		t.Fatalf("expected a no-sale result")
+	}
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	e, _ := newTestRequestEvent(app, manager, "", map[string]string{"id": auction.Id})
+	if err := handleRelistAuction(e); err != nil {
+		t.Fatalf("handleRelistAuction returned error: %v", err)
+	}
+	auction, err = app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	relisted, err := app.FindRecordById("auctions", auction.GetString("relistedAs"))
+	if err != nil {
+		t.Fatalf("failed to find relisted auction: %v", err)
+	}
+	if relisted.GetString("state") != "ongoing" || relisted.GetInt("reservePrice") != 50 || !relisted.GetDateTime("endTime").After(types.NowDateTime()) {
+		t.Fatalf("expected an ongoing copy keeping the reserve price, got state %q", relisted.GetString("state"))
+	}
+	if relisted.GetInt("buyoutPrice") != 200 || relisted.GetString("antiSnipe") != "enabled" || relisted.GetInt("antiSnipeMinutes") != 5 ||
+		relisted.GetInt("antiSnipeTriggerMinutes") != 2 || relisted.GetInt("antiSnipeCapMinutes") != 30 {
+		t.Fatalf("expected the copy to keep the buyout price and anti-snipe rule, got %v", relisted)
+	}
+	if relisted.GetInt("currentBid") != 0 || relisted.GetString("winner") != "" || relisted.GetString("relistedAs") != "" {
+		t.Fatalf("expected the copy to start without bids, got %v", relisted)
+	}
+	if err := handleRelistAuction(e); err == nil {
+		t.Fatalf("expected relisting twice to fail")
+	}
+}
+
+// TestApplyReservePrice verifies only multi-quantity winners at or above the reserve keep their unit.
+func TestApplyReservePrice(t *testing.T) {
+	app := newTestApp(t)
+
+	auction := createTestAuction(t, app, 10)
+	auction.Set("quantity", 3)
+	awards := []auctionAward{{UserId: "a", Amount: 60}, {UserId: "b", Amount: 45}, {UserId: "c", Amount: 20}}
+
+	kept := applyReservePrice(auction, awards, 40)
+	if len(kept) != 2 || auction.GetInt("currentBid") != 45 {
+		t.Fatalf("expected two awards with lowest winning bid 45, got %d at %d", len(kept), auction.GetInt("currentBid"))
+	}
+	if winners := auction.GetStringSlice("winners"); len(winners) != 2 || winners[1] != "b" {
+		t.Fatalf("expected winners to follow the kept awards, got %v", winners)
+	}
+}
+
+// TestHandleExtendAuction verifies extending moves the end time and is recorded in the event log.
+func TestHandleExtendAuction(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	auction := createTestAuction(t, app, 10)
+	previousEnd := auction.GetDateTime("endTime")
+
+	e, _ := newTestRequestEvent(app, manager, `{"minutes":30}`, map[string]string{"id": auction.Id})
+	if err := handleExtendAuction(e); err != nil {
+		t.Fatalf("handleExtendAuction returned error: %v", err)
+	}
+
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if got := auction.GetDateTime("endTime").Time().Sub(previousEnd.Time()).Round(time.Second); got != 30*time.Minute {
+		t.Fatalf("expected the end to move by 30 minutes, got %s", got)
+	}
+	event, err := app.FindFirstRecordByData("auctionEvents", "auction", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to find auction event: %v", err)
+	}
+	if event.GetString("type") != auctionLogExtended || event.GetString("actor") != manager.Id {
+		t.Fatalf("expected an extended event by the manager, got %q", event.GetString("type"))
+	}
+}
+
+// TestPauseAndResumeAuction verifies paused time does not count toward the end of the auction.
+func TestPauseAndResumeAuction(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	setTestTokens(t, app, bidder, 100)
+	auction := createTestAuction(t, app, 10)
+	previousEnd := auction.GetDateTime("endTime")
+
+	e, _ := newTestRequestEvent(app, manager, "", map[string]string{"id": auction.Id})
+	if err := handlePauseAuction(e); err != nil {
+		t.Fatalf("handlePauseAuction returned error: %v", err)
+	}
+	e, _ = newTestRequestEvent(app, bidder, `{"amount":10}`, map[string]string{"id": auction.Id})
+	if err := handleBid(e); err == nil {
+		t.Fatalf("expected bids on a paused auction to be rejected")
+	}
+
+	// pretend the auction has been paused for ten minutes
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	auction.Set("pausedAt", auction.GetDateTime("pausedAt").Add(-10*time.Minute))
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+
+	e, _ = newTestRequestEvent(app, manager, "", map[string]string{"id": auction.Id})
+	if err := handleResumeAuction(e); err != nil {
+		t.Fatalf("handleResumeAuction returned error: %v", err)
+	}
+	auction, err = app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if auction.GetString("state") != "ongoing" {
+		t.Fatalf("expected auction to be ongoing again, got %q", auction.GetString("state"))
+	}
+	if got := auction.GetDateTime("endTime").Time().Sub(previousEnd.Time()).Round(time.Second); got < 10*time.Minute || got > 11*time.Minute {
+		t.Fatalf("expected the end to move by the paused time, got %s", got)
+	}
+	count, err := app.CountRecords("auctionEvents")
+	if err != nil {
+		t.Fatalf("failed to count auction events: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected pause and resume to be logged, got %d events", count)
+	}
+}
+
+// TestBroadcastAuctionUpdate verifies the auction topic carries the leader to validated members and managers,
+// hiding anonymous bidders and sealed bids from members.
+func TestBroadcastAuctionUpdate(t *testing.T) {
+	app := newTestApp(t)
+
+	insertSettingsRecord(t, app)
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	bidder.Set("name", "Bidder")
+	if err := app.Save(bidder); err != nil {
+		t.Fatalf("failed to save bidder: %v", err)
+	}
+	member := createTestUser(t, app, "member@example.com", []string{"member"})
+	validateTestUser(t, app, member)
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	auction := createTestAuction(t, app, 10)
+	auction.Set("winner", bidder.Id)
+	auction.Set("currentBid", 25)
+	if !auctionStreamChanged(auction) {
+		t.Fatal("expected a new leading bid to change the stream")
+	}
+
+	message, ok := receiveTestAuctionUpdate(t, app, auction, member)
+	if !ok || message.Winner != bidder.Id || message.CurrentBid != 25 || len(message.WinnerNames) != 1 || message.WinnerNames[0] != "Bidder" {
+		t.Fatalf("unexpected auction update: %+v", message)
+	}
+	if _, ok := receiveTestAuctionUpdate(t, app, auction, bidder); ok {
+		t.Fatal("expected an unvalidated user to get no auction update")
+	}
+	if _, ok := receiveTestAuctionUpdate(t, app, auction, nil); ok {
+		t.Fatal("expected an anonymous client to get no auction update")
+	}
+
+	if _, err := app.DB().Update("settings", dbx.Params{"anonymousBidders": true}, nil).Execute(); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+	message, _ = receiveTestAuctionUpdate(t, app, auction, member)
+	if message.Winner != "" || len(message.Winners) != 0 || len(message.WinnerNames) != 0 || message.CurrentBid != 25 {
+		t.Fatalf("expected the bidder to be hidden, got %+v", message)
+	}
+	message, _ = receiveTestAuctionUpdate(t, app, auction, manager)
+	if message.Winner != bidder.Id {
+		t.Fatalf("expected managers to see the bidder, got %+v", message)
+	}
+
+	auction.Set("mode", auctionModeSealedFirstPrice)
+	message, _ = receiveTestAuctionUpdate(t, app, auction, member)
+	if message.Winner != "" || message.CurrentBid != 0 || message.State != "ongoing" {
+		t.Fatalf("expected the sealed bids to be hidden, got %+v", message)
+	}
+}
+
+// TestBroadcastAuctionEvent verifies logged auction events such as retractions only reach managers.
+func TestBroadcastAuctionEvent(t *testing.T) {
+	app := newTestApp(t)
+
+	bidder := createTestUser(t, app, "bidder@example.com", []string{"member"})
+	validateTestUser(t, app, bidder)
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	auction := createTestAuction(t, app, 10)
+	event, err := logAuctionEvent(app, auction.Id, auctionLogBidRetracted, bidder.Id, nil)
+	if err != nil {
+		t.Fatalf("logAuctionEvent returned error: %v", err)
+	}
+	broadcast := func() error {
+		return broadcastAuctionEvent(app, event)
+	}
+
+	raw, ok := receiveTestBroadcast(t, app, "auction_events", manager, broadcast)
+	message := AuctionEventMessage{}
+	if !ok || json.Unmarshal(raw, &message) != nil || message.Actor != bidder.Id {
+		t.Fatalf("expected the manager to get the event, got %s", raw)
+	}
+	if _, ok := receiveTestBroadcast(t, app, "auction_events", bidder, broadcast); ok {
+		t.Fatal("expected a member to get no auction event")
+	}
+}
+
+// receiveTestAuctionUpdate broadcasts an auction update and returns what a subscribed client signed in as auth received.
+func receiveTestAuctionUpdate(t *testing.T, app *pocketbase.PocketBase, auction *core.Record, auth *core.Record) (AuctionUpdateMessage, bool) {
+	t.Helper()
+
+	message := AuctionUpdateMessage{}
+	raw, ok := receiveTestBroadcast(t, app, auctionTopic(auction.Id), auth, func() error {
+		return broadcastAuctionUpdate(app, auction)
+	})
+	if ok {
+		if err := json.Unmarshal(raw, &message); err != nil {
+			t.Fatalf("failed to decode auction update: %v", err)
+		}
+	}
+	return message, ok
+}
+
+// receiveTestBroadcast runs a broadcast and returns the message a client subscribed to the topic got, if any.
+func receiveTestBroadcast(t *testing.T, app *pocketbase.PocketBase, topic string, auth *core.Record, broadcast func() error) ([]byte, bool) {
+	t.Helper()
+
+	client := subscriptions.NewDefaultClient()
+	client.Subscribe(topic)
+	if auth != nil {
+		client.Set(apis.RealtimeClientAuthKey, auth)
+	}
+	app.SubscriptionsBroker().Register(client)
+	defer app.SubscriptionsBroker().Unregister(client.Id())
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- broadcast()
+	}()
+
+	// sends block until received, so a broadcast that finishes first skipped the client
+	select {
+	case raw := <-client.Channel():
+		if err := <-errs; err != nil {
+			t.Fatalf("broadcast returned error: %v", err)
+		}
+		return raw.Data, true
+	case err := <-errs:
+		if err != nil {
+			t.Fatalf("broadcast returned error: %v", err)
+		}
+		return nil, false
+	case <-time.After(5 * time.Second):
+		t.Fatal("broadcast did not finish")
+	}
+	return nil, false
+}
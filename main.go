@@ -37,6 +37,16 @@ func main() {
 			app.Logger().Error("finishAuction error", "error", err)
 		}
 	})
+	app.Cron().MustAdd("openScheduledAuctions", "* * * * *", func() {
+		if err := openScheduledAuctions(app); err != nil {
+			app.Logger().Error("openScheduledAuctions error", "error", err)
+		}
+	})
+	app.Cron().MustAdd("notifyEndingAuctions", "* * * * *", func() {
+		if err := notifyEndingAuctions(app); err != nil {
+			app.Logger().Error("notifyEndingAuctions error", "error", err)
+		}
+	})
 	app.Cron().MustAdd("updateUserNames", "0 3 * * *", func() {
 		if err := updateUserNames(app); err != nil {
 			app.Logger().Error("updateUserNames error", "error", err)
@@ -47,6 +57,16 @@ func main() {
 			app.Logger().Error("runTokenHealthCheck error", "error", err)
 		}
 	})
+	app.Cron().MustAdd("runTokenDecay", "0 4 * * *", func() {
+		if err := runTokenDecay(app); err != nil {
+			app.Logger().Error("runTokenDecay error", "error", err)
+		}
+	})
+	app.Cron().MustAdd("cleanNotificationOutbox", "30 3 * * *", func() {
+		if err := cleanNotificationOutbox(app); err != nil {
+			app.Logger().Error("cleanNotificationOutbox error", "error", err)
+		}
+	})
 	app.Cron().MustAdd("getTLDBItems", "0 2 * * 6", func() {
 		if err := getTLDBItems(app); err != nil {
 			app.Logger().Error("getTLDBItems error", "error", err)
@@ -101,7 +121,30 @@ func main() {
 	})
 	app.OnRecordCreate("auctions").BindFunc(func(e *core.RecordEvent) error {
 		if e.Record.GetString("state") == "" {
-			e.Record.Set("state", "ongoing")
+			e.Record.Set("state", scheduledState(e.Record.GetDateTime("startTime")))
+		}
+		if e.Record.GetString("state") == "scheduled" && e.Record.GetDateTime("startTime").IsZero() {
+			return errors.New("scheduled auctions require a start time")
+		}
+		if startTime := e.Record.GetDateTime("startTime"); !startTime.IsZero() && !startTime.Before(e.Record.GetDateTime("endTime")) {
+			return errors.New("auction must start before it ends")
+		}
+		if buyoutPrice := e.Record.GetInt("buyoutPrice"); buyoutPrice > 0 {
+			if isSealedMode(e.Record.GetString("mode")) || e.Record.GetInt("quantity") > 1 {
+				return errors.New("buyout price is only supported for open single item auctions")
+			}
+			if buyoutPrice < e.Record.GetInt("startingBid") || buyoutPrice < e.Record.GetInt("reservePrice") {
+				return errors.New("buyout price must not be below the starting bid or reserve price")
+			}
+		}
+		if e.Record.GetString("mode") == "" {
+			e.Record.Set("mode", "open")
+		}
+		if e.Record.GetInt("quantity") < 1 {
+			e.Record.Set("quantity", 1)
+		}
+		if e.Record.GetString("pricing") == "" {
+			e.Record.Set("pricing", "payAsBid")
 		}
 		if e.Record.GetString("mainImage") == "" {
 			rec, err := e.App.FindFirstRecordByData("items", "name", e.Record.GetString("itemName"))
@@ -136,6 +179,37 @@ func main() {
 		}
 		return e.Next()
 	})
+	app.OnRecordAfterCreateSuccess("auctions").BindFunc(func(e *core.RecordEvent) error {
+		syncAuctionTimer(e.Record)
+		if e.Record.GetString("state") == "draft" {
+			return e.Next()
+		}
+		if err := announceAuction(e.App, auctionEventCreated, e.Record); err != nil {
+			e.App.Logger().Error("Error announcing auction", "error", err, "auctionId", e.Record.Id)
+		}
+		return e.Next()
+	})
+	app.OnRecordAfterUpdateSuccess("auctions").BindFunc(func(e *core.RecordEvent) error {
+		// keeps the close timer on the end time, which floating end moves
+		syncAuctionTimer(e.Record)
+		if auctionStreamChanged(e.Record) {
+			if err := broadcastAuctionUpdate(e.App, e.Record); err != nil {
+				e.App.Logger().Error("Error broadcasting auction update", "error", err, "auctionId", e.Record.Id)
+			}
+		}
+		return e.Next()
+	})
+	app.OnRecordAfterDeleteSuccess("auctions").BindFunc(func(e *core.RecordEvent) error {
+		cancelAuctionClose(e.Record.Id)
+		return e.Next()
+	})
+	app.OnRecordDelete("auctions").BindFunc(func(e *core.RecordEvent) error {
+		// deleted auctions must not keep tokens reserved
+		if err := releaseReservations(e.App, e.Record.Id); err != nil {
+			return err
+		}
+		return e.Next()
+	})
 	app.OnRecordCreate("settings").BindFunc(func(e *core.RecordEvent) error {
 		err := e.App.DB().Select("id").From("settings").One(nil)
 		if err == nil {
@@ -143,13 +217,29 @@ func main() {
 		}
 		return e.Next()
 	})
-	go startNotificationWorker(app.App)
+	app.OnRecordAfterCreateSuccess("notificationOutbox").BindFunc(func(e *core.RecordEvent) error {
+		wakeNotificationWorker()
+		return e.Next()
+	})
+	app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		go startNotificationWorker(se.App)
+		if err := startAuctionTimers(se.App); err != nil {
+			se.App.Logger().Error("Error scheduling auction timers", "error", err)
+		}
+		return se.Next()
+	})
 	if err := app.Start(); err != nil {
 		log.Fatal(err)
 	}
 }
 // notify sends a subscription message to connected clients.
 func notify(app core.App, subscription string, data any) error {
+	return notifyClients(app, subscription, data, nil)
+}
+
+// notifyClients sends a subscription message to the connected clients whose auth record passes the filter.
+// Without a filter every subscribed client gets it, with one anonymous clients never do.
+func notifyClients(app core.App, subscription string, data any, filter func(auth *core.Record) bool) error {
 	rawData, err := json.Marshal(data)
 	if err != nil {
 		return err
@@ -170,6 +260,12 @@ func notify(app core.App, subscription string, data any) error {
 				if !client.HasSubscription(subscription) {
 					continue
 				}
+				if filter != nil {
+					auth, _ := client.Get(apis.RealtimeClientAuthKey).(*core.Record)
+					if auth == nil || !filter(auth) {
+						continue
+					}
+				}
 
 				client.Send(message)
 			}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	_ "github.com/oldbear24/dkp-auction/migrations"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
 )
 
 // TestTranslateRarity verifies known rarity mappings.
@@ -128,3 +131,16 @@ func insertSettingsRecord(t *testing.T, app *pocketbase.PocketBase) {
 		t.Fatalf("failed to save settings record: %v", err)
 	}
 }
+
+// newTestRequestEvent builds a request event for calling route handlers directly.
+func newTestRequestEvent(app *pocketbase.PocketBase, auth *core.Record, body string, pathValues map[string]string) (*core.RequestEvent, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range pathValues {
+		req.SetPathValue(key, value)
+	}
+	rec := httptest.NewRecorder()
+
+	e := &core.RequestEvent{App: app, Auth: auth, Event: router.Event{Response: rec, Request: req}}
+	return e, rec
+}
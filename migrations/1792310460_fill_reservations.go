@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// carry over the reservations held by the current winners of ongoing auctions
+		auctions, err := app.FindRecordsByFilter("auctions", "state = 'ongoing' && winner != ''", "", 0, 0, dbx.Params{})
+		if err != nil {
+			return err
+		}
+		coll, err := app.FindCollectionByNameOrId("reservations")
+		if err != nil {
+			return err
+		}
+		for _, auction := range auctions {
+			record := core.NewRecord(coll)
+			record.Set("auction", auction.Id)
+			record.Set("user", auction.GetString("winner"))
+			record.Set("amount", auction.GetInt("currentBid"))
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		// add down queries...
+		return nil
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// copy the winner and the price of already finished auctions to their results
+		results, err := app.FindAllRecords("auctionsResult")
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			auction, err := app.FindRecordById("auctions", result.GetString("auction"))
+			if err != nil {
+				continue
+			}
+			result.Set("winner", auction.GetString("winner"))
+			result.Set("amount", auction.GetInt("currentBid"))
+			if err := app.Save(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		// add down queries...
+		return nil
+	})
+}
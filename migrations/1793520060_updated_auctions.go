@@ -0,0 +1,121 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_1337428601")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(26, []byte(`{
+			"hidden": false,
+			"id": "select2919739599",
+			"maxSelect": 1,
+			"name": "antiSnipe",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "select",
+			"values": [
+				"enabled",
+				"disabled"
+			]
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(27, []byte(`{
+			"hidden": false,
+			"id": "number1356676366",
+			"max": null,
+			"min": 0,
+			"name": "antiSnipeMinutes",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(28, []byte(`{
+			"hidden": false,
+			"id": "number612293886",
+			"max": null,
+			"min": 0,
+			"name": "antiSnipeTriggerMinutes",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(29, []byte(`{
+			"hidden": false,
+			"id": "number1601456248",
+			"max": null,
+			"min": 0,
+			"name": "antiSnipeCapMinutes",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(30, []byte(`{
+			"hidden": false,
+			"id": "number1249897466",
+			"max": null,
+			"min": 0,
+			"name": "antiSnipeExtension",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_1337428601")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("select2919739599")
+
+		// remove field
+		collection.Fields.RemoveById("number1356676366")
+
+		// remove field
+		collection.Fields.RemoveById("number612293886")
+
+		// remove field
+		collection.Fields.RemoveById("number1601456248")
+
+		// remove field
+		collection.Fields.RemoveById("number1249897466")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,140 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_419297710")
+		if err != nil {
+			return err
+		}
+
+		// update collection data
+		if err := json.Unmarshal([]byte(`{
+			"indexes": [
+				"CREATE INDEX ` + "`" + `idx_bids_auction_user` + "`" + ` ON ` + "`" + `bids` + "`" + ` (\n  ` + "`" + `auction` + "`" + `,\n  ` + "`" + `user` + "`" + `\n)"
+			]
+		}`), &collection); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(7, []byte(`{
+			"hidden": false,
+			"id": "select1602912115",
+			"maxSelect": 1,
+			"name": "source",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "select",
+			"values": [
+				"web",
+				"api",
+				"proxy"
+			]
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(8, []byte(`{
+			"hidden": false,
+			"id": "bool1928787439",
+			"name": "retracted",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "bool"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(9, []byte(`{
+			"hidden": false,
+			"id": "autodate2990389176",
+			"name": "created",
+			"onCreate": true,
+			"onUpdate": false,
+			"presentable": false,
+			"system": false,
+			"type": "autodate"
+		}`)); err != nil {
+			return err
+		}
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// existing bids keep the time of their last raise
+		if _, err := app.DB().NewQuery("UPDATE bids SET source = CASE WHEN proxy THEN 'proxy' ELSE 'web' END, created = strftime('%Y-%m-%d %H:%M:%S.000Z', CAST(timestamp AS INTEGER), 'unixepoch')").Execute(); err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("bool1270418834")
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_419297710")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(6, []byte(`{
+			"hidden": false,
+			"id": "bool1270418834",
+			"name": "proxy",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "bool"
+		}`)); err != nil {
+			return err
+		}
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// only the latest bid of each user fits the single bid per user again
+		if _, err := app.DB().Delete("bids", dbx.NewExp("retracted = TRUE")).Execute(); err != nil {
+			return err
+		}
+		if _, err := app.DB().NewQuery("DELETE FROM bids WHERE rowid NOT IN (SELECT MAX(rowid) FROM bids GROUP BY auction, user)").Execute(); err != nil {
+			return err
+		}
+		if _, err := app.DB().Update("bids", dbx.Params{"proxy": true}, dbx.HashExp{"source": "proxy"}).Execute(); err != nil {
+			return err
+		}
+
+		// update collection data
+		if err := json.Unmarshal([]byte(`{
+			"indexes": [
+				"CREATE UNIQUE INDEX ` + "`" + `idx_6jU1B7LQUw` + "`" + ` ON ` + "`" + `bids` + "`" + ` (\n  ` + "`" + `auction` + "`" + `,\n  ` + "`" + `user` + "`" + `\n)"
+			]
+		}`), &collection); err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("select1602912115")
+
+		// remove field
+		collection.Fields.RemoveById("bool1928787439")
+
+		// remove field
+		collection.Fields.RemoveById("autodate2990389176")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,163 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": null,
+			"deleteRule": null,
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text3249006413",
+					"max": 0,
+					"min": 0,
+					"name": "journal",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "select2100713124",
+					"maxSelect": 1,
+					"name": "account",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "select",
+					"values": [
+						"wallet",
+						"escrow",
+						"pool"
+					]
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation2375276105",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "user",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "pbc_1337428601",
+					"hidden": false,
+					"id": "relation3739547027",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "auction",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "number2392944706",
+					"max": null,
+					"min": null,
+					"name": "amount",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "pbc_3174063690",
+					"hidden": false,
+					"id": "relation1916208593",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "transaction",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text3485334036",
+					"max": 0,
+					"min": 0,
+					"name": "note",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_2136130477",
+			"indexes": [
+				"CREATE INDEX ` + "`" + `idx_ledgerEntries_user` + "`" + ` ON ` + "`" + `ledgerEntries` + "`" + ` (\n  ` + "`" + `user` + "`" + `,\n  ` + "`" + `account` + "`" + `\n)",
+				"CREATE INDEX ` + "`" + `idx_ledgerEntries_auction` + "`" + ` ON ` + "`" + `ledgerEntries` + "`" + ` (` + "`" + `auction` + "`" + `)",
+				"CREATE INDEX ` + "`" + `idx_ledgerEntries_journal` + "`" + ` ON ` + "`" + `ledgerEntries` + "`" + ` (` + "`" + `journal` + "`" + `)"
+			],
+			"listRule": "user = @request.auth.id",
+			"name": "ledgerEntries",
+			"system": false,
+			"type": "base",
+			"updateRule": null,
+			"viewRule": "user = @request.auth.id"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_2136130477")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
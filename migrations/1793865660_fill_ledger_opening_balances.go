@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/security"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_2136130477")
+		if err != nil {
+			return err
+		}
+		entry := func(journal string, account string, userId string, auctionId string, amount int) error {
+			record := core.NewRecord(collection)
+			record.Set("journal", journal)
+			record.Set("account", account)
+			record.Set("user", userId)
+			record.Set("auction", auctionId)
+			record.Set("amount", amount)
+			record.Set("note", "Opening balance")
+			return app.Save(record)
+		}
+
+		users, err := app.FindAllRecords("users")
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			// the guild pool funds every balance held before the ledger existed
+			journal := security.RandomStringWithAlphabet(15, "abcdefghijklmnopqrstuvwxyz0123456789")
+			if tokens := user.GetInt("tokens"); tokens != 0 {
+				if err := entry(journal, "pool", "", "", -tokens); err != nil {
+					return err
+				}
+				if err := entry(journal, "wallet", user.Id, "", tokens); err != nil {
+					return err
+				}
+			}
+
+			// reservations move into escrow, which also corrects a drifted reservedTokens
+			reservations, err := app.FindRecordsByFilter("reservations", "user = {:userId}", "", 0, 0, dbx.Params{"userId": user.Id})
+			if err != nil {
+				return err
+			}
+			reserved := 0
+			for _, reservation := range reservations {
+				amount := reservation.GetInt("amount")
+				if err := entry(journal, "wallet", user.Id, "", -amount); err != nil {
+					return err
+				}
+				if err := entry(journal, "escrow", user.Id, reservation.GetString("auction"), amount); err != nil {
+					return err
+				}
+				reserved += amount
+			}
+			if user.GetInt("reservedTokens") != reserved {
+				user.Set("reservedTokens", reserved)
+				if err := app.Save(user); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		_, err := app.DB().Delete("ledgerEntries", nil).Execute()
+		return err
+	})
+}
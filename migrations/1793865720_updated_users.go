@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("_pb_users_auth_")
+		if err != nil {
+			return err
+		}
+
+		// update collection data
+		if err := json.Unmarshal([]byte(`{
+			"updateRule": "id = @request.auth.id && \n@request.body.tokens:isset = false &&\n@request.body.reservedTokens:isset = false &&\n@request.body.role:isset = false &&\n@request.body.tokenKey:isset = false &&\n@request.body.validated:isset = false &&\n@request.body.discordId:isset = false\n\n"
+		}`), &collection); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("_pb_users_auth_")
+		if err != nil {
+			return err
+		}
+
+		// update collection data
+		if err := json.Unmarshal([]byte(`{
+			"updateRule": "id = @request.auth.id && \n@request.body.reservedTokens:isset = false &&\n@request.body.role:isset = false &&\n@request.body.tokenKey:isset = false &&\n@request.body.validated:isset = false &&\n@request.body.discordId:isset = false\n\n"
+		}`), &collection); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	})
+}
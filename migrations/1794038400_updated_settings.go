@@ -0,0 +1,146 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_2769025244")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(23, []byte(`{
+			"hidden": false,
+			"id": "select481340271",
+			"maxSelect": 1,
+			"name": "decaySchedule",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "select",
+			"values": [
+				"weekly",
+				"monthly"
+			]
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(24, []byte(`{
+			"hidden": false,
+			"id": "number2912031546",
+			"max": 100,
+			"min": 0,
+			"name": "decayPercentage",
+			"onlyInt": false,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(25, []byte(`{
+			"hidden": false,
+			"id": "number3411851936",
+			"max": null,
+			"min": 0,
+			"name": "decayFloor",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(26, []byte(`{
+			"hidden": false,
+			"id": "select953486099",
+			"maxSelect": 4,
+			"name": "decayExemptRoles",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "select",
+			"values": [
+				"member",
+				"lootCouncil",
+				"admin",
+				"manager"
+			]
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(27, []byte(`{
+			"hidden": false,
+			"id": "select371636331",
+			"maxSelect": 1,
+			"name": "decayRounding",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "select",
+			"values": [
+				"down",
+				"up",
+				"nearest"
+			]
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(28, []byte(`{
+			"hidden": false,
+			"id": "date1048796761",
+			"max": "",
+			"min": "",
+			"name": "decayLastRun",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "date"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_2769025244")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("select481340271")
+
+		// remove field
+		collection.Fields.RemoveById("number2912031546")
+
+		// remove field
+		collection.Fields.RemoveById("number3411851936")
+
+		// remove field
+		collection.Fields.RemoveById("select953486099")
+
+		// remove field
+		collection.Fields.RemoveById("select371636331")
+
+		// remove field
+		collection.Fields.RemoveById("date1048796761")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,160 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// reservations become a read-only view of the escrow ledger instead of a second copy of it
+		collection, err := app.FindCollectionByNameOrId("pbc_2495182241")
+		if err != nil {
+			return err
+		}
+		if err := app.Delete(collection); err != nil {
+			return err
+		}
+
+		view := core.NewViewCollection("reservations", "pbc_2495182241")
+		view.ViewQuery = "SELECT MIN(id) AS id, auction, user, CAST(SUM(amount) AS INT) AS amount FROM ledgerEntries WHERE account = 'escrow' GROUP BY auction, user HAVING SUM(amount) != 0"
+		view.ListRule = types.Pointer("user = @request.auth.id")
+		view.ViewRule = types.Pointer("user = @request.auth.id")
+		return app.Save(view)
+	}, func(app core.App) error {
+		view, err := app.FindCollectionByNameOrId("pbc_2495182241")
+		if err != nil {
+			return err
+		}
+		if err := app.Delete(view); err != nil {
+			return err
+		}
+
+		jsonData := `{
+			"createRule": null,
+			"deleteRule": null,
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "pbc_1337428601",
+					"hidden": false,
+					"id": "relation3739547027",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "auction",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation2375276105",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "user",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "number2392944706",
+					"max": null,
+					"min": 0,
+					"name": "amount",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_2495182241",
+			"indexes": [
+				"CREATE UNIQUE INDEX ` + "`" + `idx_Rq7mW2sLk9` + "`" + ` ON ` + "`" + `reservations` + "`" + ` (\n  ` + "`" + `auction` + "`" + `,\n  ` + "`" + `user` + "`" + `\n)"
+			],
+			"listRule": "user = @request.auth.id",
+			"name": "reservations",
+			"system": false,
+			"type": "base",
+			"updateRule": null,
+			"viewRule": "user = @request.auth.id"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// fill the mirror again from the escrow balances
+		rows := []struct {
+			Auction string `db:"auction"`
+			User    string `db:"user"`
+			Amount  int    `db:"amount"`
+		}{}
+		if err := app.DB().
+			Select("auction", "user", "SUM(amount) as amount").
+			From("ledgerEntries").
+			Where(dbx.HashExp{"account": "escrow"}).
+			GroupBy("auction", "user").
+			Having(dbx.NewExp("SUM(amount) != 0")).
+			All(&rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			record := core.NewRecord(collection)
+			record.Set("auction", row.Auction)
+			record.Set("user", row.User)
+			record.Set("amount", row.Amount)
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -1,50 +1,197 @@
 package main
 
 import (
+	"time"
+
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	outboxPending   = "pending"
+	outboxDelivered = "delivered"
+	outboxFailed    = "failed"
+	outboxMuted     = "muted"
 )
 
-// notificationChannel buffers notification payloads for async processing.
-var notificationChannel = make(chan notification, 1024)
+// outboxMaxAttempts is how many times delivery of a notification is tried before it is marked failed.
+const outboxMaxAttempts = 10
+
+// outboxPollInterval is how often the worker looks for due notifications without being woken up.
+const outboxPollInterval = 5 * time.Second
 
-// startNotificationWorker consumes queued notifications and persists them.
+// notificationWake signals the worker that new notifications were committed.
+var notificationWake = make(chan struct{}, 1)
+
+// startNotificationWorker delivers pending outbox notifications until the process exits.
 func startNotificationWorker(app core.App) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
 	for {
-		n := <-notificationChannel
-		userIds := []string{}
-		if n.IsRole {
-			users, err := app.FindRecordsByFilter("users", "role:each ?= {:role}", "", 0, 0, dbx.Params{"role": n.UserIdOrRole})
-			if err != nil {
-				app.Logger().Error("Error finding users by role", "error", err)
-				continue
-			}
-			for _, user := range users {
-				userIds = append(userIds, user.Id)
+		if err := deliverPendingNotifications(app); err != nil {
+			app.Logger().Error("Error delivering notifications", "error", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-notificationWake:
+		}
+	}
+}
+
+// wakeNotificationWorker asks the worker to look for new notifications without blocking the caller.
+func wakeNotificationWorker() {
+	select {
+	case notificationWake <- struct{}{}:
+	default:
+	}
+}
+
+// deliverPendingNotifications delivers every outbox notification that is due.
+// Failed deliveries are retried with an exponential backoff.
+func deliverPendingNotifications(app core.App) error {
+	records, err := app.FindRecordsByFilter(
+		"notificationOutbox",
+		"state = {:state} && nextAttempt <= @now",
+		"created",
+		100,
+		0,
+		dbx.Params{"state": outboxPending},
+	)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		attempts := record.GetInt("attempts") + 1
+		allowed, err := notificationAllowed(app, record.GetString("user"), record.GetString("event"), record.GetString("channel"))
+		if err == nil && !allowed {
+			record.Set("state", outboxMuted)
+			if err := app.Save(record); err != nil {
+				return err
 			}
+			continue
+		}
+		channel, err := findDeliveryChannel(record.GetString("channel"))
+		if err == nil {
+			err = deliverOutboxRecord(app, channel, record, attempts)
+		}
+		if err == nil {
+			continue
+		}
+
+		app.Logger().Error("Error delivering notification", "error", err, "outboxId", record.Id, "channel", record.GetString("channel"))
+		record.Set("attempts", attempts)
+		record.Set("lastError", err.Error())
+		if attempts >= outboxMaxAttempts {
+			record.Set("state", outboxFailed)
 		} else {
-			userIds = append(userIds, n.UserIdOrRole)
+			record.Set("state", outboxPending)
+			record.Set("nextAttempt", time.Now().UTC().Add(outboxBackoff(attempts)))
 		}
-		for _, userId := range userIds {
-			if err := createNotification(app, userId, n.Message); err != nil {
-				app.Logger().Error("Error creating notification", "error", err)
-			}
+		// the failure may come from the record itself, store the bookkeeping regardless
+		if err := app.SaveNoValidate(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliverOutboxRecord delivers an outbox record through its channel and stores it as delivered.
+// In-app delivery only writes locally, so it commits together with the outbox state. Other channels
+// are at-least-once, a crash before the state is saved repeats them.
+func deliverOutboxRecord(app core.App, channel deliveryChannel, record *core.Record, attempts int) error {
+	deliver := func(app core.App) error {
+		if err := channel.Deliver(app, record); err != nil {
+			return err
+		}
+		record.Set("state", outboxDelivered)
+		record.Set("attempts", attempts)
+		return app.Save(record)
+	}
+	if channel.Name() == channelInApp {
+		return app.RunInTransaction(deliver)
+	}
+	return deliver(app)
+}
+
+// outboxBackoff returns the delay before the next delivery attempt, doubling per attempt up to an hour.
+func outboxBackoff(attempts int) time.Duration {
+	delay := time.Second << min(attempts, 12)
+	return min(delay, time.Hour)
+}
+
+// cleanNotificationOutbox removes delivered notifications older than the retention period.
+func cleanNotificationOutbox(app core.App) error {
+	_, err := app.DB().Delete("notificationOutbox", dbx.And(
+		dbx.HashExp{"state": outboxDelivered},
+		dbx.NewExp("updated < {:before}", dbx.Params{"before": types.NowDateTime().AddDate(0, 0, -30)}),
+	)).Execute()
+	return err
+}
+
+// notifyUser stores a notification for a specific user in the outbox, once per channel reaching the user.
+// Pass the transaction of the business change so a rollback discards the notification too.
+// The user's preferences for the event are applied when the worker delivers it.
+func notifyUser(app core.App, userId string, event NotificationEvent) error {
+	message, err := renderNotification(event)
+	if err != nil {
+		return err
+	}
+	settings, err := GetSettings(app)
+	if err != nil {
+		return err
+	}
+	user, err := app.FindRecordById("users", userId)
+	if err != nil {
+		return err
+	}
+	for _, channel := range deliveryChannels {
+		payload, ok := channel.Payload(settings, user, event, message)
+		if !ok {
+			continue
+		}
+		if err := enqueueNotification(app, channel.Name(), userId, event.Type, message, payload); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-// notifyUser enqueues a notification for a specific user.
-func notifyUser(userId string, message string) {
-	notificationChannel <- notification{UserIdOrRole: userId, Message: message, IsRole: false}
+// enqueueNotification stores a single outbox record for a channel.
+func enqueueNotification(app core.App, channel string, userId string, event string, message string, payload any) error {
+	coll, err := app.FindCachedCollectionByNameOrId("notificationOutbox")
+	if err != nil {
+		return err
+	}
+	record := core.NewRecord(coll)
+	record.Set("user", userId)
+	record.Set("channel", channel)
+	record.Set("event", event)
+	record.Set("message", message)
+	if payload != nil {
+		record.Set("payload", payload)
+	}
+	record.Set("state", outboxPending)
+	record.Set("nextAttempt", types.NowDateTime())
+	return app.Save(record)
 }
 
-// notifyRole enqueues a notification for all users with a role.
-func notifyRole(role string, message string) {
-	notificationChannel <- notification{UserIdOrRole: role, Message: message, IsRole: true}
+// notifyRole stores a notification in the outbox for all users with a role.
+func notifyRole(app core.App, role string, event NotificationEvent) error {
+	users, err := app.FindRecordsByFilter("users", "role:each ?= {:role}", "", 0, 0, dbx.Params{"role": role})
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := notifyUser(app, user.Id, event); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // createNotification writes a notification record for the given user.
-func createNotification(app core.App, userId string, message string) error {
+func createNotification(app core.App, userId string, event NotificationEvent, message string) error {
 	coll, err := app.FindCachedCollectionByNameOrId("notifications")
 	if err != nil {
 		return err
@@ -52,14 +199,15 @@ func createNotification(app core.App, userId string, message string) error {
 	record := core.NewRecord(coll)
 	record.Set("user", userId)
 	record.Set("text", message)
+	record.Set("type", event.Type)
+	record.Set("auction", event.Auction)
+	record.Set("amount", event.Amount)
+	record.Set("actor", event.Actor)
+	if event.Data != nil {
+		record.Set("data", event.Data)
+	}
 	if err := app.Save(record); err != nil {
 		return err
 	}
 	return nil
 }
-
-type notification struct {
-	UserIdOrRole string
-	Message      string
-	IsRole       bool
-}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// TestNotifyUserRollsBackWithTransaction ensures a rolled back change leaves no notification behind.
+func TestNotifyUserRollsBackWithTransaction(t *testing.T) {
+	app := newTestApp(t)
+
+	user := createTestUser(t, app, "outbid@example.com", []string{"member"})
+
+	err := app.RunInTransaction(func(tx core.App) error {
+		if err := notifyUser(tx, user.Id, NotificationEvent{Type: eventOutbid, Amount: 10}); err != nil {
+			return err
+		}
+		return errors.New("bid rejected")
+	})
+	if err == nil {
+		t.Fatalf("expected transaction to fail")
+	}
+
+	count, err := app.CountRecords("notificationOutbox")
+	if err != nil {
+		t.Fatalf("failed to count outbox records: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no outbox records after rollback, got %d", count)
+	}
+}
+
+// TestDeliverPendingNotifications verifies queued notifications are delivered once.
+func TestDeliverPendingNotifications(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	createTestUser(t, app, "member@example.com", []string{"member"})
+
+	if err := notifyRole(app.App, "manager", NotificationEvent{Type: eventAuctionEnded}); err != nil {
+		t.Fatalf("notifyRole returned error: %v", err)
+	}
+	for range 2 {
+		if err := deliverPendingNotifications(app.App); err != nil {
+			t.Fatalf("deliverPendingNotifications returned error: %v", err)
+		}
+	}
+
+	notifications, err := app.FindAllRecords("notifications")
+	if err != nil {
+		t.Fatalf("failed to find notifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].GetString("user") != manager.Id {
+		t.Fatalf("expected a single notification for the manager, got %d", len(notifications))
+	}
+
+	outbox, err := app.FindFirstRecordByData("notificationOutbox", "user", manager.Id)
+	if err != nil {
+		t.Fatalf("failed to find outbox record: %v", err)
+	}
+	if outbox.GetString("state") != outboxDelivered {
+		t.Fatalf("expected outbox record to be delivered, got %q", outbox.GetString("state"))
+	}
+}
+
+// TestDeliverPendingNotificationsRetries verifies failed deliveries are rescheduled.
+func TestDeliverPendingNotificationsRetries(t *testing.T) {
+	app := newTestApp(t)
+
+	user := createTestUser(t, app, "gone@example.com", []string{"member"})
+	if err := notifyUser(app.App, user.Id, NotificationEvent{Type: eventWon}); err != nil {
+		t.Fatalf("notifyUser returned error: %v", err)
+	}
+	// remove the user behind the outbox's back so delivery fails
+	if _, err := app.DB().Delete("users", dbx.HashExp{"id": user.Id}).Execute(); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	if err := deliverPendingNotifications(app.App); err != nil {
+		t.Fatalf("deliverPendingNotifications returned error: %v", err)
+	}
+
+	outbox, err := app.FindFirstRecordByData("notificationOutbox", "user", user.Id)
+	if err != nil {
+		t.Fatalf("failed to find outbox record: %v", err)
+	}
+	if outbox.GetString("state") != outboxPending || outbox.GetInt("attempts") != 1 || outbox.GetString("lastError") == "" {
+		t.Fatalf("expected a rescheduled delivery, got state %q after %d attempts", outbox.GetString("state"), outbox.GetInt("attempts"))
+	}
+	if !outbox.GetDateTime("nextAttempt").After(outbox.GetDateTime("created")) {
+		t.Fatalf("expected next attempt to be delayed")
+	}
+}
+
+// TestDeliverPendingNotificationsInAppIsAtomic ensures an in-app notification is rolled back when its outbox state cannot be saved.
+func TestDeliverPendingNotificationsInAppIsAtomic(t *testing.T) {
+	app := newTestApp(t)
+
+	user := createTestUser(t, app, "member@example.com", []string{"member"})
+	if err := notifyUser(app.App, user.Id, NotificationEvent{Type: eventWon}); err != nil {
+		t.Fatalf("notifyUser returned error: %v", err)
+	}
+	app.OnRecordUpdate("notificationOutbox").BindFunc(func(e *core.RecordEvent) error {
+		if e.Record.GetString("state") == outboxDelivered {
+			return errors.New("outbox unavailable")
+		}
+		return e.Next()
+	})
+
+	if err := deliverPendingNotifications(app.App); err != nil {
+		t.Fatalf("deliverPendingNotifications returned error: %v", err)
+	}
+	if count, err := app.CountRecords("notifications"); err != nil || count != 0 {
+		t.Fatalf("expected the notification to be rolled back, got %d (%v)", count, err)
+	}
+	outbox, err := app.FindFirstRecordByFilter("notificationOutbox", "user = {:userId} && channel = {:channel}", dbx.Params{"userId": user.Id, "channel": channelInApp})
+	if err != nil {
+		t.Fatalf("failed to find outbox record: %v", err)
+	}
+	if outbox.GetString("state") != outboxPending || outbox.GetInt("attempts") != 1 {
+		t.Fatalf("expected a rescheduled delivery, got state %q after %d attempts", outbox.GetString("state"), outbox.GetInt("attempts"))
+	}
+}
+
+// TestNotificationStoresEvent verifies delivered notifications keep the structured event next to the rendered text.
+func TestNotificationStoresEvent(t *testing.T) {
+	app := newTestApp(t)
+
+	user := createTestUser(t, app, "outbid@example.com", []string{"member"})
+	rival := createTestUser(t, app, "rival@example.com", []string{"member"})
+	auction := createTestAuction(t, app, 10)
+
+	event := NotificationEvent{Type: eventOutbid, Auction: auction.Id, Amount: 25, Actor: rival.Id}
+	if err := notifyUser(app.App, user.Id, event); err != nil {
+		t.Fatalf("notifyUser returned error: %v", err)
+	}
+	if err := deliverPendingNotifications(app.App); err != nil {
+		t.Fatalf("deliverPendingNotifications returned error: %v", err)
+	}
+
+	notification, err := app.FindFirstRecordByData("notifications", "user", user.Id)
+	if err != nil {
+		t.Fatalf("failed to find notification: %v", err)
+	}
+	if notification.GetString("text") != "Your bid was outbid by 25 tokens" {
+		t.Fatalf("unexpected notification text %q", notification.GetString("text"))
+	}
+	if notification.GetString("type") != eventOutbid || notification.GetString("auction") != auction.Id ||
+		notification.GetInt("amount") != 25 || notification.GetString("actor") != rival.Id {
+		t.Fatalf("expected the outbid event to be stored, got type %q auction %q amount %d actor %q",
+			notification.GetString("type"), notification.GetString("auction"), notification.GetInt("amount"), notification.GetString("actor"))
+	}
+}
+
+// TestRenderNotification verifies templates use the event fields and reject unknown event types.
+func TestRenderNotification(t *testing.T) {
+	text, err := renderNotification(NotificationEvent{Type: eventTokenChange, Amount: -5, Data: map[string]any{"reason": "Raid penalty"}})
+	if err != nil {
+		t.Fatalf("renderNotification returned error: %v", err)
+	}
+	if text != "Your tokens have been updated by -5. Reason: Raid penalty" {
+		t.Fatalf("unexpected text %q", text)
+	}
+
+	text, err = renderNotification(NotificationEvent{Type: eventAuctionEnded})
+	if err != nil {
+		t.Fatalf("renderNotification returned error: %v", err)
+	}
+	if text != "Auction has ended" {
+		t.Fatalf("unexpected text %q", text)
+	}
+
+	if _, err := renderNotification(NotificationEvent{Type: "unknown"}); err == nil {
+		t.Fatalf("expected an error for an unknown event type")
+	}
+}
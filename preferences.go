@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	eventOutbid             = "outbid"
+	eventWon                = "won"
+	eventResultVoided       = "resultVoided"
+	eventTokenChange        = "tokenChange"
+	eventAuctionOpened      = "auctionOpened"
+	eventAuctionEnding      = "auctionEnding"
+	eventAuctionEnded       = "auctionEnded"
+	eventAuctionCancelled   = "auctionCancelled"
+	eventAuctionUnsold      = "auctionUnsold"
+	eventHealthCheckFailure = "healthCheckFailure"
+	eventItemsUpdated       = "itemsUpdated"
+)
+
+// notificationEvents lists the event types users can configure.
+var notificationEvents = []string{
+	eventOutbid,
+	eventWon,
+	eventResultVoided,
+	eventTokenChange,
+	eventAuctionOpened,
+	eventAuctionEnding,
+	eventAuctionEnded,
+	eventAuctionCancelled,
+	eventAuctionUnsold,
+	eventHealthCheckFailure,
+	eventItemsUpdated,
+}
+
+// findNotificationPreferences returns the preference record of a user, or nil when the user kept the defaults.
+func findNotificationPreferences(app core.App, userId string) (*core.Record, error) {
+	record, err := app.FindFirstRecordByData("notificationPreferences", "user", userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// loadNotificationPreferences returns the preferences of a user with every event and channel filled in.
+// Events and channels without a stored choice are enabled.
+func loadNotificationPreferences(app core.App, userId string) (NotificationPreferences, error) {
+	preferences := NotificationPreferences{Events: map[string]map[string]bool{}}
+	record, err := findNotificationPreferences(app, userId)
+	if err != nil {
+		return preferences, err
+	}
+	if record != nil {
+		if raw := record.GetString("events"); raw != "" && raw != "null" {
+			if err := record.UnmarshalJSONField("events", &preferences.Events); err != nil {
+				return preferences, err
+			}
+		}
+		if mutedUntil := record.GetDateTime("mutedUntil"); !mutedUntil.IsZero() {
+			preferences.MutedUntil = mutedUntil.Time().Format(time.RFC3339)
+		}
+	}
+	for _, event := range notificationEvents {
+		if preferences.Events[event] == nil {
+			preferences.Events[event] = map[string]bool{}
+		}
+		for _, channel := range deliveryChannels {
+			if _, ok := preferences.Events[event][channel.Name()]; !ok {
+				preferences.Events[event][channel.Name()] = true
+			}
+		}
+	}
+	return preferences, nil
+}
+
+// notificationAllowed reports whether a user wants an event delivered on a channel right now.
+func notificationAllowed(app core.App, userId string, event string, channel string) (bool, error) {
+	if userId == "" || event == "" {
+		return true, nil
+	}
+	preferences, err := loadNotificationPreferences(app, userId)
+	if err != nil {
+		return true, err
+	}
+	if preferences.MutedUntil != "" {
+		mutedUntil, err := types.ParseDateTime(preferences.MutedUntil)
+		if err == nil && mutedUntil.After(types.NowDateTime()) {
+			return false, nil
+		}
+	}
+	if channel == "" {
+		channel = channelInApp
+	}
+	return preferences.Events[event][channel], nil
+}
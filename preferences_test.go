@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// TestMutedEventIsNotDelivered ensures a disabled event and channel is marked muted instead of delivered.
+func TestMutedEventIsNotDelivered(t *testing.T) {
+	app := newTestApp(t)
+
+	user := createTestUser(t, app, "quiet@example.com", []string{"member"})
+	saveTestPreferences(t, app.App, user.Id, map[string]map[string]bool{
+		eventOutbid: {channelInApp: false},
+	}, types.DateTime{})
+
+	if err := notifyUser(app.App, user.Id, NotificationEvent{Type: eventOutbid}); err != nil {
+		t.Fatalf("notifyUser returned error: %v", err)
+	}
+	if err := notifyUser(app.App, user.Id, NotificationEvent{Type: eventWon}); err != nil {
+		t.Fatalf("notifyUser returned error: %v", err)
+	}
+	if err := deliverPendingNotifications(app.App); err != nil {
+		t.Fatalf("deliverPendingNotifications returned error: %v", err)
+	}
+
+	assertOutboxState(t, app.App, user.Id, eventOutbid, outboxMuted)
+	assertOutboxState(t, app.App, user.Id, eventWon, outboxDelivered)
+}
+
+// TestMutedUntilSilencesEveryEvent verifies a temporary mute blocks delivery until it expires.
+func TestMutedUntilSilencesEveryEvent(t *testing.T) {
+	app := newTestApp(t)
+
+	user := createTestUser(t, app, "away@example.com", []string{"member"})
+	mutedUntil := types.NowDateTime().Add(time.Hour)
+	saveTestPreferences(t, app.App, user.Id, nil, mutedUntil)
+
+	allowed, err := notificationAllowed(app.App, user.Id, eventWon, channelInApp)
+	if err != nil {
+		t.Fatalf("notificationAllowed returned error: %v", err)
+	}
+	if allowed {
+		t.Fatalf("expected notifications to be muted until %s", mutedUntil)
+	}
+
+	saveTestPreferences(t, app.App, user.Id, nil, types.NowDateTime().Add(-time.Hour))
+	allowed, err = notificationAllowed(app.App, user.Id, eventWon, channelInApp)
+	if err != nil {
+		t.Fatalf("notificationAllowed returned error: %v", err)
+	}
+	if !allowed {
+		t.Fatalf("expected notifications to be delivered after the mute expired")
+	}
+}
+
+func saveTestPreferences(t *testing.T, app core.App, userId string, events map[string]map[string]bool, mutedUntil types.DateTime) {
+	t.Helper()
+	record, err := findNotificationPreferences(app, userId)
+	if err != nil {
+		t.Fatalf("failed to find preferences: %v", err)
+	}
+	if record == nil {
+		coll, err := app.FindCollectionByNameOrId("notificationPreferences")
+		if err != nil {
+			t.Fatalf("failed to find preferences collection: %v", err)
+		}
+		record = core.NewRecord(coll)
+		record.Set("user", userId)
+	}
+	record.Set("events", events)
+	record.Set("mutedUntil", mutedUntil)
+	if err := app.Save(record); err != nil {
+		t.Fatalf("failed to save preferences: %v", err)
+	}
+}
+
+func assertOutboxState(t *testing.T, app core.App, userId string, event string, state string) {
+	t.Helper()
+	record, err := app.FindFirstRecordByFilter("notificationOutbox", "user = {:user} && event = {:event}", dbx.Params{"user": userId, "event": event})
+	if err != nil {
+		t.Fatalf("failed to find %s outbox record: %v", event, err)
+	}
+	if record.GetString("state") != state {
+		t.Fatalf("expected %s notification to be %q, got %q", event, state, record.GetString("state"))
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	repairModeTransactions = "transactions"
+	repairModeBalances     = "balances"
+)
+
+const (
+	repairTransaction = "transaction"
+	repairBalance     = "balance"
+	repairReservation = "reservation"
+	repairCache       = "cache"
+	repairTokens      = "tokens"
+)
+
+// sumTransactions returns the sum of all transactions listed for a user.
+func sumTransactions(app core.App, userId string) (int, error) {
+	var sum struct {
+		Amount int `db:"amount"`
+	}
+	err := app.DB().
+		Select("COALESCE(SUM(amount), 0) as amount").
+		From("transactions").
+		Where(dbx.HashExp{"user": userId}).
+		One(&sum)
+	return sum.Amount, err
+}
+
+// planHealthCheckRepair lists the changes that fix the users a health check flagged.
+// Everything is recomputed from the current state, so a repair that was already applied plans nothing.
+// The transactions mode lists adjustment transactions for token differences, the balances mode moves
+// the balances to the transaction sum instead. Reservations are set to what their auctions expect and the cached
+// tokens and reservedTokens are refreshed from the ledger either way.
+func planHealthCheckRepair(app core.App, healthCheck *core.Record, mode string) ([]HealthCheckRepairChange, error) {
+	if mode != repairModeTransactions && mode != repairModeBalances {
+		return nil, fmt.Errorf("unknown repair mode %q", mode)
+	}
+	report := TokenHealtCheck{}
+	if err := json.Unmarshal([]byte(healthCheck.GetString("result")), &report); err != nil {
+		return nil, err
+	}
+	settings, err := GetSettings(app)
+	if err != nil {
+		return nil, err
+	}
+	expected, active, err := expectedReservations(app, settings)
+	if err != nil {
+		return nil, err
+	}
+	holdings, err := findEscrowHoldings(app)
+	if err != nil {
+		return nil, err
+	}
+	issues := reservationIssues(holdings, expected, active)
+
+	changes := []HealthCheckRepairChange{}
+	for _, result := range report.UserResults {
+		if result.State == "ok" {
+			continue
+		}
+		user, err := app.FindRecordById("users", result.User)
+		if err != nil {
+			return nil, err
+		}
+		transactionTokens, err := sumTransactions(app, user.Id)
+		if err != nil {
+			return nil, err
+		}
+		wallet, err := ledgerBalance(app, ledgerWallet, dbx.HashExp{"user": user.Id})
+		if err != nil {
+			return nil, err
+		}
+		escrow, err := ledgerBalance(app, ledgerEscrow, dbx.HashExp{"user": user.Id})
+		if err != nil {
+			return nil, err
+		}
+		// the ledger holds the real balance, the tokens field is only a cache of it
+		if difference := wallet + escrow - transactionTokens; difference != 0 {
+			if mode == repairModeTransactions {
+				changes = append(changes, HealthCheckRepairChange{User: user.Id, Kind: repairTransaction, Amount: difference})
+			} else {
+				changes = append(changes, HealthCheckRepairChange{User: user.Id, Kind: repairBalance, Amount: -difference})
+			}
+		}
+		for _, issue := range issues[user.Id] {
+			changes = append(changes, HealthCheckRepairChange{
+				User:    user.Id,
+				Kind:    repairReservation,
+				Auction: issue.Auction,
+				Amount:  issue.Expected - issue.Reserved,
+			})
+		}
+		if user.GetInt("tokens") != wallet+escrow {
+			changes = append(changes, HealthCheckRepairChange{User: user.Id, Kind: repairTokens, Amount: wallet + escrow - user.GetInt("tokens")})
+		}
+		if user.GetInt("reservedTokens") != escrow {
+			changes = append(changes, HealthCheckRepairChange{User: user.Id, Kind: repairCache, Amount: escrow - user.GetInt("reservedTokens")})
+		}
+	}
+	return changes, nil
+}
+
+// applyHealthCheckRepair applies planned repair changes and records who approved them.
+func applyHealthCheckRepair(app core.App, healthCheck *core.Record, mode string, changes []HealthCheckRepairChange, approvedBy string) (*core.Record, error) {
+	for _, change := range changes {
+		var err error
+		switch change.Kind {
+		case repairTransaction:
+			_, err = createTransactionRecord(app, change.User, change.Amount, "Health check adjustment", approvedBy)
+		case repairBalance:
+			err = postJournal(app, "", "Health check adjustment",
+				ledgerEntry{Account: ledgerPool, Amount: -change.Amount},
+				ledgerEntry{Account: ledgerWallet, UserId: change.User, Amount: change.Amount},
+			)
+		case repairReservation:
+			var reserved int
+			reserved, err = getReservation(app, change.Auction, change.User)
+			if err == nil {
+				err = setReservation(app, change.Auction, change.User, reserved+change.Amount)
+			}
+		case repairCache, repairTokens:
+			err = refreshUserBalance(app, change.User)
+		default:
+			err = fmt.Errorf("unknown repair change %q", change.Kind)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	coll, err := app.FindCachedCollectionByNameOrId("healthCheckRepairs")
+	if err != nil {
+		return nil, err
+	}
+	record := core.NewRecord(coll)
+	record.Set("healthCheck", healthCheck.Id)
+	record.Set("mode", mode)
+	record.Set("approvedBy", approvedBy)
+	record.Set("changes", changes)
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
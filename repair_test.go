@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// TestRepairHealthCheckAddsTransactions verifies a dry run changes nothing and an applied repair is audited and idempotent.
+func TestRepairHealthCheckAddsTransactions(t *testing.T) {
+	app := newTestApp(t)
+
+	admin := createTestUser(t, app, "admin@example.com", []string{"admin"})
+	user := createTestUser(t, app, "user@example.com", []string{"member"})
+	setTestTokens(t, app, user, 100)
+	// a transaction listed without moving any tokens
+	if _, err := createTransactionRecord(app, user.Id, 30, "Stray transaction", ""); err != nil {
+		t.Fatalf("createTransactionRecord returned error: %v", err)
+	}
+	healthCheck := runTestHealthCheck(t, app, "error")
+
+	changes := repairTestHealthCheck(t, app, admin, healthCheck, `{"mode":"transactions","dryRun":true}`)
+	if len(changes) != 1 || changes[0].Kind != repairTransaction || changes[0].Amount != -30 {
+		t.Fatalf("expected one adjustment transaction of -30, got %+v", changes)
+	}
+	if sum, _ := sumTransactions(app, user.Id); sum != 130 {
+		t.Fatalf("expected the dry run to keep transactions at 130, got %d", sum)
+	}
+	if count, _ := app.CountRecords("healthCheckRepairs"); count != 0 {
+		t.Fatalf("expected the dry run not to be audited, got %d repairs", count)
+	}
+
+	repairTestHealthCheck(t, app, admin, healthCheck, `{"mode":"transactions"}`)
+	assertTokens(t, app, user.Id, 100)
+	if sum, _ := sumTransactions(app, user.Id); sum != 100 {
+		t.Fatalf("expected transactions to match the balance, got %d", sum)
+	}
+	repairs, err := app.FindAllRecords("healthCheckRepairs", dbx.HashExp{"healthCheck": healthCheck.Id})
+	if err != nil || len(repairs) != 1 {
+		t.Fatalf("expected one audited repair, got %d: %v", len(repairs), err)
+	}
+	if repairs[0].GetString("approvedBy") != admin.Id || repairs[0].GetString("mode") != repairModeTransactions {
+		t.Fatalf("expected the repair to be approved by the admin, got %v", repairs[0])
+	}
+	runTestHealthCheck(t, app, "ok")
+
+	if changes := repairTestHealthCheck(t, app, admin, healthCheck, `{"mode":"transactions"}`); len(changes) != 0 {
+		t.Fatalf("expected a repeated repair to change nothing, got %+v", changes)
+	}
+}
+
+// TestRepairHealthCheckResetsBalances verifies the balances mode moves tokens and fixes reservations.
+func TestRepairHealthCheckResetsBalances(t *testing.T) {
+	app := newTestApp(t)
+
+	admin := createTestUser(t, app, "admin@example.com", []string{"admin"})
+	user := createTestUser(t, app, "user@example.com", []string{"member"})
+	setTestTokens(t, app, user, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, user, auction.Id, `{"amount":20}`)
+	if _, err := createTransactionRecord(app, user.Id, 30, "Stray transaction", ""); err != nil {
+		t.Fatalf("createTransactionRecord returned error: %v", err)
+	}
+	if err := moveToEscrow(app, auction.Id, user.Id, 5); err != nil {
+		t.Fatalf("moveToEscrow returned error: %v", err)
+	}
+	healthCheck := runTestHealthCheck(t, app, "error")
+
+	repairTestHealthCheck(t, app, admin, healthCheck, `{"mode":"balances"}`)
+	assertTokens(t, app, user.Id, 130)
+	assertReservedTokens(t, app, user.Id, 20)
+	runTestHealthCheck(t, app, "ok")
+}
+
+// TestRepairHealthCheckRefreshesTokens verifies a cached balance that drifted from the ledger is flagged and refreshed.
+func TestRepairHealthCheckRefreshesTokens(t *testing.T) {
+	app := newTestApp(t)
+
+	admin := createTestUser(t, app, "admin@example.com", []string{"admin"})
+	user := createTestUser(t, app, "user@example.com", []string{"member"})
+	setTestTokens(t, app, user, 100)
+	// the cache and the transactions agree, only the ledger tells the balance is wrong
+	if _, err := app.DB().Update("users", dbx.Params{"tokens": 130}, dbx.HashExp{"id": user.Id}).Execute(); err != nil {
+		t.Fatalf("failed to update tokens: %v", err)
+	}
+	if _, err := createTransactionRecord(app, user.Id, 30, "Stray transaction", ""); err != nil {
+		t.Fatalf("createTransactionRecord returned error: %v", err)
+	}
+	healthCheck := runTestHealthCheck(t, app, "error")
+
+	changes := repairTestHealthCheck(t, app, admin, healthCheck, `{"mode":"transactions","dryRun":true}`)
+	if len(changes) != 2 || changes[0].Kind != repairTransaction || changes[0].Amount != -30 ||
+		changes[1].Kind != repairTokens || changes[1].Amount != -30 {
+		t.Fatalf("expected an adjustment transaction and a tokens refresh of -30, got %+v", changes)
+	}
+	repairTestHealthCheck(t, app, admin, healthCheck, `{"mode":"transactions"}`)
+	assertTokens(t, app, user.Id, 100)
+	runTestHealthCheck(t, app, "ok")
+}
+
+// TestRepairHealthCheckRequiresAdmin verifies only admins can repair a health check.
+func TestRepairHealthCheckRequiresAdmin(t *testing.T) {
+	app := newTestApp(t)
+
+	manager := createTestUser(t, app, "manager@example.com", []string{"manager"})
+	healthCheck := runTestHealthCheck(t, app, "ok")
+
+	e, _ := newTestRequestEvent(app, manager, `{"mode":"balances"}`, map[string]string{"id": healthCheck.Id})
+	if err := repairHealthCheck(e); err == nil {
+		t.Fatal("expected a manager to be rejected")
+	}
+}
+
+// runTestHealthCheck runs the token health check, checks its state and returns the stored record.
+func runTestHealthCheck(t *testing.T, app *pocketbase.PocketBase, state string) *core.Record {
+	t.Helper()
+
+	if err := runTokenHealthCheck(app); err != nil {
+		t.Fatalf("runTokenHealthCheck returned error: %v", err)
+	}
+	records, err := app.FindRecordsByFilter("tokenHealthChecks", "", "-@rowid", 1, 0)
+	if err != nil || len(records) == 0 {
+		t.Fatalf("failed to find health check: %v", err)
+	}
+	if got := records[0].GetString("state"); got != state {
+		t.Fatalf("expected health check state %q, got %q: %s", state, got, records[0].GetString("result"))
+	}
+	return records[0]
+}
+
+// repairTestHealthCheck calls repairHealthCheck as the admin and returns the listed changes.
+func repairTestHealthCheck(t *testing.T, app *pocketbase.PocketBase, admin *core.Record, healthCheck *core.Record, body string) []HealthCheckRepairChange {
+	t.Helper()
+
+	e, rec := newTestRequestEvent(app, admin, body, map[string]string{"id": healthCheck.Id})
+	if err := repairHealthCheck(e); err != nil {
+		t.Fatalf("repairHealthCheck returned error: %v", err)
+	}
+	var response struct {
+		Changes []HealthCheckRepairChange `json:"changes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return response.Changes
+}
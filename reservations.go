@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// findReservation returns the reservations view record of a user for an auction, or nil when nothing is held in escrow.
+func findReservation(app core.App, auctionId string, userId string) (*core.Record, error) {
+	record, err := app.FindFirstRecordByFilter(
+		"reservations",
+		"auction = {:auctionId} && user = {:userId}",
+		dbx.Params{"auctionId": auctionId, "userId": userId},
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// getReservation returns the amount of tokens a user holds in reserve for an auction.
+func getReservation(app core.App, auctionId string, userId string) (int, error) {
+	return escrowBalance(app, auctionId, userId)
+}
+
+// setReservation sets the tokens a user holds in escrow for an auction.
+// users.reservedTokens is refreshed by the ledger and the reservations view reads the escrow entries directly.
+func setReservation(app core.App, auctionId string, userId string, amount int) error {
+	previous, err := escrowBalance(app, auctionId, userId)
+	if err != nil {
+		return err
+	}
+	if previous == amount {
+		return nil
+	}
+	return moveToEscrow(app, auctionId, userId, amount-previous)
+}
+
+// releaseReservations returns every token held in escrow for an auction to the bidders' wallets.
+func releaseReservations(app core.App, auctionId string) error {
+	holders, err := escrowHolders(app, auctionId)
+	if err != nil {
+		return err
+	}
+	for userId := range holders {
+		if err := setReservation(app, auctionId, userId, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// availableTokens returns the tokens a user can still commit to an auction,
+// counting what they already hold in reserve for it.
+func availableTokens(app core.App, user *core.Record, auctionId string) (int, error) {
+	reserved, err := getReservation(app, auctionId, user.Id)
+	if err != nil {
+		return 0, err
+	}
+	return user.GetInt("tokens") - user.GetInt("reservedTokens") + reserved, nil
+}
+
+// reservationRange is the span of tokens a bidder is expected to hold in reserve for an auction.
+// Reserving the proxy maximum lets the leader hold anything up to their maximum bid.
+type reservationRange struct {
+	Min int
+	Max int
+}
+
+// expectedReservations recomputes from the auctions still taking bids what every bidder should hold in reserve,
+// keyed by user and auction. It also returns the ids of those auctions.
+func expectedReservations(app core.App, settings *Settings) (map[string]map[string]reservationRange, map[string]bool, error) {
+	auctions, err := app.FindRecordsByFilter("auctions", "state = 'ongoing' || state = 'paused'", "", 0, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	active := map[string]bool{}
+	expected := map[string]map[string]reservationRange{}
+	add := func(userId string, auctionId string, amount reservationRange) {
+		if expected[userId] == nil {
+			expected[userId] = map[string]reservationRange{}
+		}
+		expected[userId][auctionId] = amount
+	}
+	for _, auction := range auctions {
+		active[auction.Id] = true
+		if !isSealedMode(auction.GetString("mode")) && auction.GetInt("quantity") <= 1 {
+			winnerId := auction.GetString("winner")
+			if winnerId == "" {
+				continue
+			}
+			currentBid := auction.GetInt("currentBid")
+			amount := reservationRange{Min: currentBid, Max: currentBid}
+			if settings.ProxyReserveMaximum {
+				bid, err := findBid(app, auction.Id, winnerId)
+				if err != nil {
+					return nil, nil, err
+				}
+				if bid != nil {
+					amount.Max = max(currentBid, bid.GetInt("maxAmount"))
+				}
+			}
+			add(winnerId, auction.Id, amount)
+			continue
+		}
+
+		ranked, err := findRankedBids(app, auction.Id)
+		if err != nil {
+			return nil, nil, err
+		}
+		winnerIds := auction.GetStringSlice("winners")
+		for _, bid := range ranked {
+			// sealed bids are all held until the auction closes, multi-unit bids only while they win a unit
+			if isSealedMode(auction.GetString("mode")) || slices.Contains(winnerIds, bid.UserId) {
+				add(bid.UserId, auction.Id, reservationRange{Min: bid.Amount, Max: bid.Amount})
+			}
+		}
+	}
+	return expected, active, nil
+}
+
+// reservationIssues compares the escrow holdings with the expected reservations and lists the discrepancies per user.
+// Holdings on auctions that no longer take bids or no longer exist are orphaned.
+func reservationIssues(holdings []escrowHolding, expected map[string]map[string]reservationRange, active map[string]bool) map[string][]TokenHealtCheckReservation {
+	issues := map[string][]TokenHealtCheckReservation{}
+	held := map[string]map[string]bool{}
+	for _, holding := range holdings {
+		if held[holding.User] == nil {
+			held[holding.User] = map[string]bool{}
+		}
+		held[holding.User][holding.Auction] = true
+
+		amount, ok := expected[holding.User][holding.Auction]
+		issue := ""
+		switch {
+		case holding.Amount < 0:
+			issue = "negative"
+		case !active[holding.Auction]:
+			issue = "orphaned"
+		case !ok || holding.Amount < amount.Min || holding.Amount > amount.Max:
+			issue = "mismatch"
+		}
+		if issue != "" {
+			issues[holding.User] = append(issues[holding.User], TokenHealtCheckReservation{
+				Auction:  holding.Auction,
+				Issue:    issue,
+				Reserved: holding.Amount,
+				Expected: amount.Min,
+			})
+		}
+	}
+	for userId, auctions := range expected {
+		for auctionId, amount := range auctions {
+			if amount.Min > 0 && !held[userId][auctionId] {
+				issues[userId] = append(issues[userId], TokenHealtCheckReservation{
+					Auction:  auctionId,
+					Issue:    "missing",
+					Expected: amount.Min,
+				})
+			}
+		}
+	}
+	return issues
+}
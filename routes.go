@@ -1,8 +1,10 @@
 package main
 
 import (
-	"fmt"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pocketbase/dbx"
@@ -17,12 +19,26 @@ func RegisterRoutes(se *core.ServeEvent) {
 	se.Router.POST("/api/change-tokens", chaneUsersAmount).Bind(apis.RequireAuth())
 	se.Router.POST("/api/set-validated/{user}", setVerified).Bind(apis.RequireAuth())
 	se.Router.POST("/api/resolve-auction/{id}", resolveAuction).Bind(apis.RequireAuth())
+	se.Router.POST("/api/void-auction-result/{id}", voidAuction).Bind(apis.RequireAuth())
+	se.Router.POST("/api/publish-auction/{id}", publishAuction).Bind(apis.RequireAuth())
+	se.Router.POST("/api/cancel-auction/{id}", handleCancelAuction).Bind(apis.RequireAuth())
+	se.Router.POST("/api/close-auction/{id}", handleCloseAuction).Bind(apis.RequireAuth())
+	se.Router.POST("/api/extend-auction/{id}", handleExtendAuction).Bind(apis.RequireAuth())
+	se.Router.POST("/api/pause-auction/{id}", handlePauseAuction).Bind(apis.RequireAuth())
+	se.Router.POST("/api/resume-auction/{id}", handleResumeAuction).Bind(apis.RequireAuth())
+	se.Router.POST("/api/relist-auction/{id}", handleRelistAuction).Bind(apis.RequireAuth())
 	se.Router.POST("/api/seen-notifications/{id}", seenNotification).Bind(apis.RequireAuth())
 	se.Router.POST("/api/seen-notifications", seenNotifications).Bind(apis.RequireAuth())
 	se.Router.POST("/api/clear-tokens", clearTokens).Bind(apis.RequireAuth())
 	se.Router.POST("/api/add-to-favourites/{id}", addToFavourites).Bind(apis.RequireAuth())
 	se.Router.POST("/api/remove-from-favourites/{id}", removeFromFavourites).Bind(apis.RequireAuth())
 	se.Router.GET("/api/dashboard-stats", getDashboardStats).Bind(apis.RequireAuth())
+	se.Router.POST("/api/repair-health-check/{id}", repairHealthCheck).Bind(apis.RequireAuth())
+	se.Router.POST("/api/retract-bid/{id}", handleRetractBid).Bind(apis.RequireAuth())
+	se.Router.GET("/api/next-bid/{id}", getNextBid).Bind(apis.RequireAuth())
+	se.Router.GET("/api/bid-history/{id}", getBidHistory).Bind(apis.RequireAuth())
+	se.Router.GET("/api/notification-preferences", getNotificationPreferences).Bind(apis.RequireAuth())
+	se.Router.POST("/api/notification-preferences", setNotificationPreferences).Bind(apis.RequireAuth())
 
 }
 
@@ -91,6 +107,282 @@ func resolveAuction(e *core.RequestEvent) error {
 	})
 }
 
+// publishAuction opens a draft auction now or schedules it for its start time.
+func publishAuction(e *core.RequestEvent) error {
+	var data struct {
+		StartTime string `json:"startTime"`
+	}
+	if !checkIfUserIsInRole(e.Auth, "manager") {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	if err := e.BindBody(&data); err != nil {
+		return e.BadRequestError("Invalid data", err)
+	}
+	return e.App.RunInTransaction(func(tx core.App) error {
+		auction, err := tx.FindRecordById("auctions", e.Request.PathValue("id"))
+		if err != nil {
+			return e.NotFoundError("Auction not found", err)
+		}
+		if auction.GetString("state") != "draft" {
+			return e.BadRequestError("Only draft auctions can be published", nil)
+		}
+		if data.StartTime != "" {
+			startTime, err := types.ParseDateTime(data.StartTime)
+			if err != nil {
+				return e.BadRequestError("Invalid start time", err)
+			}
+			auction.Set("startTime", startTime)
+		}
+		startTime := auction.GetDateTime("startTime")
+		if !startTime.IsZero() && !startTime.Before(auction.GetDateTime("endTime")) {
+			return e.BadRequestError("Auction must start before it ends", nil)
+		}
+		if auction.GetDateTime("endTime").Before(types.NowDateTime()) {
+			return e.BadRequestError("Auction has ended", nil)
+		}
+
+		if scheduledState(startTime) == "ongoing" {
+			if err := openAuction(tx, auction); err != nil {
+				return e.BadRequestError("Error opening auction", err)
+			}
+		} else {
+			auction.Set("state", "scheduled")
+			if err := tx.Save(auction); err != nil {
+				return e.BadRequestError("Error saving auction", err)
+			}
+			if err := announceAuction(tx, auctionEventCreated, auction); err != nil {
+				return e.BadRequestError("Error announcing auction", err)
+			}
+		}
+		return e.JSON(200, map[string]interface{}{
+			"success": true,
+			"state":   auction.GetString("state"),
+		})
+	})
+}
+
+// handleCloseAuction closes an ongoing auction immediately, settling it like an expired one.
+func handleCloseAuction(e *core.RequestEvent) error {
+	if !checkIfUserIsInRole(e.Auth, "manager") {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	auctionId := e.Request.PathValue("id")
+	if auctionId == "" {
+		return e.BadRequestError("Auction ID is required", nil)
+	}
+	event, err := closeAuctionNow(e.App, auctionId, e.Auth.Id)
+	if err != nil {
+		return e.BadRequestError("Error closing auction", err)
+	}
+	if event != nil {
+		if err := broadcastAuctionEvent(e.App, event); err != nil {
+			e.App.Logger().Error("Error broadcasting auction event", "error", err, "auctionId", auctionId)
+		}
+	}
+	return e.JSON(200, map[string]interface{}{
+		"success": true,
+		"closed":  event != nil,
+	})
+}
+
+// handleExtendAuction moves the end of an auction later by a number of minutes.
+func handleExtendAuction(e *core.RequestEvent) error {
+	var data struct {
+		Minutes int `json:"minutes"`
+	}
+	if !checkIfUserIsInRole(e.Auth, "manager") {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	if err := e.BindBody(&data); err != nil {
+		return e.BadRequestError("Invalid data", err)
+	}
+	if data.Minutes <= 0 {
+		return e.BadRequestError("Minutes must be positive", nil)
+	}
+	return manageAuction(e, []string{"ongoing", "paused"}, func(tx core.App, auction *core.Record) (*core.Record, error) {
+		previousEnd := auction.GetDateTime("endTime")
+		if err := extendAuction(tx, auction, data.Minutes); err != nil {
+			return nil, err
+		}
+		return logAuctionEvent(tx, auction.Id, auctionLogExtended, e.Auth.Id, map[string]any{
+			"minutes":     data.Minutes,
+			"previousEnd": previousEnd,
+			"endTime":     auction.GetDateTime("endTime"),
+		})
+	})
+}
+
+// handlePauseAuction stops an auction from taking bids until it is resumed.
+func handlePauseAuction(e *core.RequestEvent) error {
+	if !checkIfUserIsInRole(e.Auth, "manager") {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	return manageAuction(e, []string{"ongoing"}, func(tx core.App, auction *core.Record) (*core.Record, error) {
+		if err := pauseAuction(tx, auction); err != nil {
+			return nil, err
+		}
+		return logAuctionEvent(tx, auction.Id, auctionLogPaused, e.Auth.Id, nil)
+	})
+}
+
+// handleResumeAuction reopens a paused auction without counting the paused time toward its end.
+func handleResumeAuction(e *core.RequestEvent) error {
+	if !checkIfUserIsInRole(e.Auth, "manager") {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	return manageAuction(e, []string{"paused"}, func(tx core.App, auction *core.Record) (*core.Record, error) {
+		paused, err := resumeAuction(tx, auction)
+		if err != nil {
+			return nil, err
+		}
+		return logAuctionEvent(tx, auction.Id, auctionLogResumed, e.Auth.Id, map[string]any{
+			"pausedSeconds": int(paused.Seconds()),
+			"endTime":       auction.GetDateTime("endTime"),
+		})
+	})
+}
+
+// manageAuction runs a manager action on an auction in one of the allowed states and broadcasts the logged event.
+func manageAuction(e *core.RequestEvent, states []string, action func(tx core.App, auction *core.Record) (*core.Record, error)) error {
+	auctionId := e.Request.PathValue("id")
+	if auctionId == "" {
+		return e.BadRequestError("Auction ID is required", nil)
+	}
+	var auction, event *core.Record
+	err := e.App.RunInTransaction(func(tx core.App) error {
+		var err error
+		auction, err = tx.FindRecordById("auctions", auctionId)
+		if err != nil {
+			return e.NotFoundError("Auction not found", err)
+		}
+		if !slices.Contains(states, auction.GetString("state")) {
+			return e.BadRequestError("Auction is "+auction.GetString("state"), nil)
+		}
+		event, err = action(tx, auction)
+		if err != nil {
+			return e.BadRequestError("Error updating auction", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := broadcastAuctionEvent(e.App, event); err != nil {
+		e.App.Logger().Error("Error broadcasting auction event", "error", err, "auctionId", auctionId)
+	}
+	return e.JSON(200, map[string]interface{}{
+		"success": true,
+		"auction": auction,
+	})
+}
+
+// handleCancelAuction cancels an auction that has not finished and releases all reserved tokens.
+func handleCancelAuction(e *core.RequestEvent) error {
+	var data struct {
+		Reason string `json:"reason"`
+	}
+	if !checkIfUserIsInRole(e.Auth, "manager") {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	if err := e.BindBody(&data); err != nil {
+		return e.BadRequestError("Invalid data", err)
+	}
+	if strings.TrimSpace(data.Reason) == "" {
+		return e.BadRequestError("Reason is required", nil)
+	}
+	return e.App.RunInTransaction(func(tx core.App) error {
+		auction, err := tx.FindRecordById("auctions", e.Request.PathValue("id"))
+		if err != nil {
+			return e.NotFoundError("Auction not found", err)
+		}
+		switch auction.GetString("state") {
+		case "draft", "scheduled", "ongoing", "paused":
+		case "finished":
+			return e.BadRequestError("Finished auctions cannot be cancelled, void the auction result instead", nil)
+		case "cancelled":
+			return e.BadRequestError("Auction is already cancelled", nil)
+		default:
+			return e.BadRequestError("Closed auctions cannot be cancelled", nil)
+		}
+		if err := cancelAuction(tx, auction, strings.TrimSpace(data.Reason), e.Auth.Id); err != nil {
+			return e.BadRequestError("Error cancelling auction", err)
+		}
+		return e.JSON(200, map[string]interface{}{
+			"success": true,
+		})
+	})
+}
+
+// handleRelistAuction puts an unsold auction up for bidding again as a new auction.
+func handleRelistAuction(e *core.RequestEvent) error {
+	if !checkIfUserIsInRole(e.Auth, "manager") {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	return e.App.RunInTransaction(func(tx core.App) error {
+		auction, err := tx.FindRecordById("auctions", e.Request.PathValue("id"))
+		if err != nil {
+			return e.NotFoundError("Auction not found", err)
+		}
+		if auction.GetString("state") != "unsold" {
+			return e.BadRequestError("Only unsold auctions can be relisted", nil)
+		}
+		if auction.GetString("relistedAs") != "" {
+			return e.BadRequestError("Auction was already relisted", nil)
+		}
+		relisted, err := relistAuction(tx, auction)
+		if err != nil {
+			return e.BadRequestError("Error relisting auction", err)
+		}
+		return e.JSON(200, map[string]interface{}{
+			"success": true,
+			"auction": relisted,
+		})
+	})
+}
+
+// voidAuction reverses a finished auction result and optionally hands the item to the runner-up.
+func voidAuction(e *core.RequestEvent) error {
+	var data struct {
+		Reason   string `json:"reason"`
+		Reassign bool   `json:"reassign"`
+	}
+	if !checkIfUserIsInRole(e.Auth, "manager") {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	if err := e.BindBody(&data); err != nil {
+		return e.BadRequestError("Invalid data", err)
+	}
+	if strings.TrimSpace(data.Reason) == "" {
+		return e.BadRequestError("Reason is required", nil)
+	}
+	return e.App.RunInTransaction(func(tx core.App) error {
+		result, err := tx.FindRecordById("auctionsResult", e.Request.PathValue("id"))
+		if err != nil {
+			return e.NotFoundError("Auction result not found", err)
+		}
+		if result.GetBool("voided") {
+			return e.BadRequestError("Auction result is already voided", nil)
+		}
+		if result.GetString("winner") == "" {
+			return e.BadRequestError("Auction result has no winner", nil)
+		}
+		if err := voidAuctionResult(tx, result, strings.TrimSpace(data.Reason), e.Auth.Id); err != nil {
+			return e.BadRequestError("Error voiding auction result", err)
+		}
+		var reassigned *core.Record
+		if data.Reassign {
+			reassigned, err = reassignAuctionResult(tx, result)
+			if err != nil {
+				return e.BadRequestError("Error reassigning auction", err)
+			}
+		}
+		return e.JSON(200, map[string]interface{}{
+			"success":    true,
+			"reassigned": reassigned,
+		})
+	})
+}
+
 // setVerified updates the validated flag for a user.
 func setVerified(e *core.RequestEvent) error {
 	var data struct {
@@ -154,20 +446,20 @@ func chaneUsersAmount(e *core.RequestEvent) error {
 				return e.BadRequestError("User not found", err)
 			}
 
-			newAmount := user.GetInt("tokens") + data.Amount
-
-			user.Set("tokens", newAmount)
-			if err := tx.Save(user); err != nil {
-				return e.BadRequestError("Error saving user", err)
-			}
-
-			if err := createTransactionRecord(tx, user.Id, data.Amount, message, e.Auth.Id); err != nil {
-				return e.BadRequestError("Failed to create transaction record", err)
+			if err := transferTokens(tx, user.Id, data.Amount, message, e.Auth.Id); err != nil {
+				return e.BadRequestError("Error transferring tokens", err)
 			}
 
 		}
 		for _, r := range data.UserIds {
-			notifyUser(r, fmt.Sprintf("Your tokens have been updated by %d. Reason: %s", data.Amount, message))
+			if err := notifyUser(tx, r, NotificationEvent{
+				Type:   eventTokenChange,
+				Amount: data.Amount,
+				Actor:  e.Auth.Id,
+				Data:   map[string]any{"reason": message},
+			}); err != nil {
+				return e.BadRequestError("Failed to create notification", err)
+			}
 		}
 		return e.JSON(200, map[string]interface{}{
 			"success": true,
@@ -175,10 +467,17 @@ func chaneUsersAmount(e *core.RequestEvent) error {
 	})
 }
 
-// handleBid validates and records a bid on an auction.
+// handleBid records a bid placed from the web UI.
 func handleBid(e *core.RequestEvent) error {
+	return placeBid(e, bidSourceWeb)
+}
+
+// placeBid validates and records a bid on an auction, tagging it with where it was placed from.
+// A bid may carry a hidden maximum, up to which the server raises it automatically when outbid.
+func placeBid(e *core.RequestEvent, source string) error {
 	var bidData struct {
-		Amount int `json:"amount"`
+		Amount    int `json:"amount"`
+		MaxAmount int `json:"maxAmount"`
 	}
 
 	if err := e.BindBody(&bidData); err != nil {
@@ -208,116 +507,477 @@ func handleBid(e *core.RequestEvent) error {
 
 		}
 		// 2. Validate auction state
-		if auction.GetString("state") != "ongoing" {
+		switch auction.GetString("state") {
+		case "ongoing":
+		case "draft", "scheduled":
+			return e.BadRequestError("Auction has not started yet", nil)
+		case "paused":
+			return e.BadRequestError("Auction is paused", nil)
+		default:
 			return e.BadRequestError("Auction is not active", nil)
 		}
+		if startTime := auction.GetDateTime("startTime"); !startTime.IsZero() && startTime.After(types.NowDateTime()) {
+			return e.BadRequestError("Auction has not started yet", nil)
+		}
 
 		// 3. Get user
 		user, err := tx.FindRecordById("users", e.Auth.Id)
 		if err != nil {
 			return e.NotFoundError("User not found", err)
 		}
+		if isSealedMode(auction.GetString("mode")) {
+			return handleSealedBid(e, tx, auction, user, bidData.Amount, bidData.MaxAmount, source)
+		}
 
 		// 4. Validate bid amount
+		rule, err := bidIncrementRule(settings, auction)
+		if err != nil {
+			return e.BadRequestError("Invalid bid increment rule", err)
+		}
+		if auction.GetInt("quantity") > 1 {
+			return handleMultiUnitBid(e, tx, settings, rule, auction, user, bidData.Amount, bidData.MaxAmount, source)
+		}
 		currentBid := auction.GetInt("currentBid")
-		startingBid := auction.GetInt("startingBid")
-		minBid := max(startingBid, currentBid+1)
+		minBid := minimumBid(rule, auction.GetInt("startingBid"), currentBid)
+		leaderId := auction.GetString("winner")
+		isLeader := leaderId == e.Auth.Id
 
-		if bidData.Amount < minBid {
+		amount := bidData.Amount
+		if amount == 0 && bidData.MaxAmount > 0 {
+			// A bare maximum starts at the lowest valid bid, the leader keeps their current bid
+			amount = minBid
+			if isLeader {
+				amount = currentBid
+			}
+		} else if amount < minBid {
 			return e.BadRequestError("Bid is too low", nil)
 		}
-		existingBids, err := tx.FindRecordsByFilter(
-			"bids",
-			"auction = {:auctionId} && user = {:userId}",
-			"",
-			1,
-			0,
-			dbx.Params{"auctionId": auctionId, "userId": e.Auth.Id},
-		)
-		if err != nil {
-			return e.BadRequestError("Error checking existing bids", err)
-		}
-		existinBidForCompare := 0
-		if len(existingBids) > 0 {
-			existinBidForCompare = existingBids[0].GetInt("amount")
+		maxAmount := amount
+		if bidData.MaxAmount > 0 {
+			if bidData.MaxAmount < amount {
+				return e.BadRequestError("Maximum bid is lower than the bid", nil)
+			}
+			maxAmount = bidData.MaxAmount
 		}
+
 		// 5. Check user balance
-		availableTokens := 0
-		if e.Auth.Id == auction.GetString("winner") {
-			availableTokens = user.GetInt("tokens") - user.GetInt("reservedTokens") + existinBidForCompare
-		} else {
-			availableTokens = user.GetInt("tokens") - user.GetInt("reservedTokens")
+		available, err := availableTokens(tx, user, auctionId)
+		if err != nil {
+			return e.BadRequestError("Error checking reserved tokens", err)
 		}
-		e.App.Logger().Debug("Bid tokens", "user", user.GetInt("tokens"), "res", user.GetInt("reservedTokens"), "exBid", existinBidForCompare, "all", availableTokens)
-		if bidData.Amount > availableTokens {
+		e.App.Logger().Debug("Bid tokens", "user", user.GetInt("tokens"), "res", user.GetInt("reservedTokens"), "all", available)
+		if amount > available || (settings.ProxyReserveMaximum && maxAmount > available) {
 			return e.BadRequestError("Insufficient tokens", nil)
 		}
+		bidderMax := min(maxAmount, available)
 
-		// 6. Get existing bid
-
-		// 7. Create or update bid
-		var bidRecord *core.Record
-		if len(existingBids) == 0 {
-			collection, err := tx.FindCachedCollectionByNameOrId("bids")
+		// 6. Resolve the bid against the leader's maximum
+		result := proxyResult{WinnerId: e.Auth.Id, CurrentBid: amount}
+		leaderMax := currentBid
+		var leader *core.Record
+		if leaderId != "" && !isLeader {
+			leaderBid, err := findBid(tx, auctionId, leaderId)
 			if err != nil {
-				return e.BadRequestError("Error creating bid", err)
+				return e.BadRequestError("Error finding leading bid", err)
 			}
-			bidRecord = core.NewRecord(collection)
-			bidRecord.Set("auction", auctionId)
-			bidRecord.Set("user", e.Auth.Id)
-		} else {
-			bidRecord = existingBids[0]
-		}
-
-		// 8. Update bid and user records
-		bidRecord.Set("amount", bidData.Amount)
-		bidRecord.Set("timestamp", time.Now().Unix())
-
-		//Reset previsou winner tokens
-		previsousWinnerId := auction.GetString("winner")
-		if previsousWinnerId != "" && previsousWinnerId != e.Auth.Id {
-			previsousWinner, err := tx.FindRecordById("users", previsousWinnerId)
+			leader, err = tx.FindRecordById("users", leaderId)
 			if err != nil {
 				return e.BadRequestError("Error finding previous winner", err)
 			}
-			previsousWinner.Set("reservedTokens", previsousWinner.GetInt("reservedTokens")-auction.GetInt("currentBid"))
-			if err := tx.Save(previsousWinner); err != nil {
-				return e.BadRequestError("Error saving previous winner", err)
+			leaderAvailable, err := availableTokens(tx, leader, auctionId)
+			if err != nil {
+				return e.BadRequestError("Error checking reserved tokens", err)
 			}
-			// Notify previous winner
-			notifyUser(previsousWinner.Id, fmt.Sprintf("Your bid was outbid by %d tokens", bidData.Amount))
+			if leaderBid != nil {
+				leaderMax = max(currentBid, min(leaderBid.GetInt("maxAmount"), leaderAvailable))
+			}
+			result = resolveProxyBid(proxyContest{
+				LeaderId:   leaderId,
+				CurrentBid: currentBid,
+				LeaderMax:  leaderMax,
+				BidderId:   e.Auth.Id,
+				Amount:     amount,
+				BidderMax:  bidderMax,
+			}, rule)
 		}
-		tokensToReserve := 0
-		if e.Auth.Id == auction.GetString("winner") {
-			tokensToReserve = bidData.Amount - existinBidForCompare
-		} else {
-			tokensToReserve = bidData.Amount
-		}
-		user.Set("reservedTokens", user.GetInt("reservedTokens")+tokensToReserve)
-		auction.Set("currentBid", bidData.Amount)
-		auction.Set("winner", e.Auth.Id)
-		if settings.EnableFloatingEndOfAuction {
-			newEndTime := time.Now().UTC().Add(time.Minute * time.Duration(settings.FloatingEndOfAuctionMinutes))
-			if newEndTime.After(auction.GetDateTime("endTime").Time()) {
-				auction.Set("endTime", newEndTime)
+
+		// 7. A contest reaching the buyout price sells the auction to its winner at the buyout price.
+		// Once the bids passed the buyout price it no longer applies.
+		if buyoutPrice := auction.GetInt("buyoutPrice"); buyoutPrice > currentBid && result.CurrentBid >= buyoutPrice {
+			buyer := user
+			if result.WinnerId != e.Auth.Id {
+				buyer = leader
 			}
+			return handleBuyout(e, tx, auction, buyer, buyoutPrice, amount, maxAmount, source)
 		}
 
-		// 9. Save all changes
-		if err := tx.Save(bidRecord); err != nil {
+		// 8. Record the bid and every automatic raise
+		bidRecord, err := saveBid(tx, auctionId, e.Auth.Id, amount, maxAmount, source, "")
+		if err != nil {
 			return e.BadRequestError("Error saving bid", err)
 		}
-		if err := tx.Save(user); err != nil {
+		for _, step := range result.Steps {
+			if _, err := saveBid(tx, auctionId, step.UserId, step.Amount, 0, bidSourceProxy, bidRecord.Id); err != nil {
+				return e.BadRequestError("Error saving bid", err)
+			}
+		}
+
+		// 9. Move reserved tokens to the leader
+		winnerMax := leaderMax
+		if result.WinnerId == e.Auth.Id {
+			winnerMax = bidderMax
+		}
+		tokensToReserve := result.CurrentBid
+		if settings.ProxyReserveMaximum {
+			tokensToReserve = max(winnerMax, result.CurrentBid)
+		}
+		if leaderId != "" && leaderId != result.WinnerId {
+			if err := setReservation(tx, auctionId, leaderId, 0); err != nil {
+				return e.BadRequestError("Error saving previous winner", err)
+			}
+			// Notify previous winner
+			if err := notifyUser(tx, leaderId, NotificationEvent{Type: eventOutbid, Auction: auctionId, Amount: result.CurrentBid, Actor: e.Auth.Id}); err != nil {
+				return e.BadRequestError("Failed to create notification", err)
+			}
+		}
+		if result.WinnerId != e.Auth.Id {
+			if err := notifyUser(tx, e.Auth.Id, NotificationEvent{Type: eventOutbid, Auction: auctionId, Amount: result.CurrentBid, Actor: result.WinnerId}); err != nil {
+				return e.BadRequestError("Failed to create notification", err)
+			}
+		}
+		if err := setReservation(tx, auctionId, result.WinnerId, tokensToReserve); err != nil {
 			return e.BadRequestError("Error updating user tokens", err)
 		}
+
+		auction.Set("currentBid", result.CurrentBid)
+		auction.Set("winner", result.WinnerId)
+		applyAntiSnipe(antiSnipeRule(settings, auction), auction, time.Now().UTC())
+
+		// 10. Save all changes
 		if err := tx.Save(auction); err != nil {
 			return e.BadRequestError("Error updating auction", err)
 		}
 
 		return e.JSON(200, map[string]interface{}{
-			"success": true,
-			"bid":     bidRecord,
+			"success":    true,
+			"bid":        bidRecord,
+			"leading":    result.WinnerId == e.Auth.Id,
+			"currentBid": result.CurrentBid,
+		})
+	})
+}
+
+// handleBuyout sells an auction to the buyer at its buyout price and closes it in the bid's transaction.
+// The buyer is the bidder, or the leader when their maximum beats the bid that reached the buyout price.
+func handleBuyout(e *core.RequestEvent, tx core.App, auction *core.Record, buyer *core.Record, price int, amount int, maxAmount int, source string) error {
+	bidAmount, bidMax := price, price
+	if buyer.Id != e.Auth.Id {
+		bidAmount, bidMax = amount, maxAmount
+	}
+	bidRecord, err := saveBid(tx, auction.Id, e.Auth.Id, bidAmount, bidMax, source, "")
+	if err != nil {
+		return e.BadRequestError("Error saving bid", err)
+	}
+	if buyer.Id != e.Auth.Id {
+		if _, err := saveBid(tx, auction.Id, buyer.Id, price, 0, bidSourceProxy, bidRecord.Id); err != nil {
+			return e.BadRequestError("Error saving bid", err)
+		}
+	}
+	for _, userId := range []string{auction.GetString("winner"), e.Auth.Id} {
+		if userId == "" || userId == buyer.Id {
+			continue
+		}
+		if err := notifyUser(tx, userId, NotificationEvent{Type: eventOutbid, Auction: auction.Id, Amount: price, Actor: buyer.Id}); err != nil {
+			return e.BadRequestError("Failed to create notification", err)
+		}
+	}
+
+	auction.Set("winner", buyer.Id)
+	auction.Set("currentBid", price)
+	auction.Set("endTime", types.NowDateTime())
+	closed, err := claimAuctionClose(tx, auction)
+	if err != nil {
+		return e.BadRequestError("Error finishing auction", err)
+	}
+	if !closed {
+		return e.BadRequestError("Auction is not active", nil)
+	}
+	return e.JSON(200, map[string]interface{}{
+		"success":    true,
+		"bid":        bidRecord,
+		"leading":    buyer.Id == e.Auth.Id,
+		"currentBid": price,
+		"finished":   true,
+	})
+}
+
+// getNextBid returns the lowest valid next bid of an auction so the UI can suggest it.
+func getNextBid(e *core.RequestEvent) error {
+	auctionId := e.Request.PathValue("id")
+	if auctionId == "" {
+		return e.BadRequestError("Auction ID is required", nil)
+	}
+	auction, err := e.App.FindRecordById("auctions", auctionId)
+	if err != nil {
+		return e.NotFoundError("Auction not found", err)
+	}
+	settings, err := GetSettings(e.App)
+	if err != nil {
+		return e.BadRequestError("Error getting settings", err)
+	}
+	rule, err := bidIncrementRule(settings, auction)
+	if err != nil {
+		return e.BadRequestError("Invalid bid increment rule", err)
+	}
+
+	currentBid := auction.GetInt("currentBid")
+	minBid := minimumBid(rule, auction.GetInt("startingBid"), currentBid)
+	if isSealedMode(auction.GetString("mode")) {
+		minBid = openingBid(auction.GetInt("startingBid"))
+	} else if quantity := auction.GetInt("quantity"); quantity > 1 {
+		ranked, err := findRankedBids(e.App, auction.Id)
+		if err != nil {
+			return e.BadRequestError("Error checking existing bids", err)
+		}
+		minBid = multiUnitMinimumBid(rule, auction.GetInt("startingBid"), ranked, quantity, e.Auth.Id)
+	}
+	return e.JSON(200, map[string]interface{}{
+		"currentBid": currentBid,
+		"minimumBid": minBid,
+		"increment":  minimumIncrement(rule, currentBid),
+	})
+}
+
+// getBidHistory returns every bid of an auction in the order it was placed.
+// Only validated users can read it, like the auctions themselves. Bids of a sealed auction stay hidden from other bidders
+// until it has a result, anonymous bidders are shown without their name or id.
+func getBidHistory(e *core.RequestEvent) error {
+	auctionId := e.Request.PathValue("id")
+	if auctionId == "" {
+		return e.BadRequestError("Auction ID is required", nil)
+	}
+	auction, err := e.App.FindRecordById("auctions", auctionId)
+	if err != nil {
+		return e.NotFoundError("Auction not found", err)
+	}
+	records, err := findBidHistory(e.App, auctionId)
+	if err != nil {
+		return e.BadRequestError("Error finding bids", err)
+	}
+	settings, err := GetSettings(e.App)
+	if err != nil {
+		return e.BadRequestError("Error getting settings", err)
+	}
+	isManager := checkIfUserIsInRole(e.Auth, "manager")
+	if !e.Auth.GetBool("validated") && !isManager {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	// Sealed bids stay hidden until the auction has a result
+	state := auction.GetString("state")
+	hidden := isSealedMode(auction.GetString("mode")) && state != "finished" && state != "unsold" && !isManager
+	anonymous := settings.AnonymousBidders && !isManager
+
+	userIds := []string{}
+	for _, record := range records {
+		userIds = append(userIds, record.GetString("user"))
+	}
+	users, err := e.App.FindRecordsByIds("users", userIds)
+	if err != nil {
+		return e.BadRequestError("Error finding bidders", err)
+	}
+	names := map[string]string{}
+	for _, user := range users {
+		names[user.Id] = user.GetString("name")
+	}
+
+	history := []BidHistoryEntry{}
+	for _, record := range records {
+		if hidden && record.GetString("user") != e.Auth.Id {
+			continue
+		}
+		userId := record.GetString("user")
+		userName := names[userId]
+		if anonymous && userId != e.Auth.Id {
+			userId, userName = "", ""
+		}
+		history = append(history, BidHistoryEntry{
+			Id:        record.Id,
+			User:      userId,
+			UserName:  userName,
+			Amount:    record.GetInt("amount"),
+			Source:    record.GetString("source"),
+			Retracted: record.GetBool("retracted"),
+			Created:   record.GetDateTime("created").String(),
+		})
+	}
+	return e.JSON(200, history)
+}
+
+// handleMultiUnitBid records a bid on an auction of several identical units.
+// The top bids hold one unit each and reserve their full amount, bids pushed out of the top are released.
+func handleMultiUnitBid(e *core.RequestEvent, tx core.App, settings *Settings, rule BidIncrementRule, auction *core.Record, user *core.Record, amount int, maxAmount int, source string) error {
+	if maxAmount > 0 {
+		return e.BadRequestError("Maximum bids are not supported in multi-quantity auctions", nil)
+	}
+	quantity := auction.GetInt("quantity")
+	ranked, err := findRankedBids(tx, auction.Id)
+	if err != nil {
+		return e.BadRequestError("Error checking existing bids", err)
+	}
+	if amount < multiUnitMinimumBid(rule, auction.GetInt("startingBid"), ranked, quantity, user.Id) {
+		return e.BadRequestError("Bid is too low", nil)
+	}
+	available, err := availableTokens(tx, user, auction.Id)
+	if err != nil {
+		return e.BadRequestError("Error checking reserved tokens", err)
+	}
+	if amount > available {
+		return e.BadRequestError("Insufficient tokens", nil)
+	}
+
+	bidRecord, err := saveBid(tx, auction.Id, user.Id, amount, amount, source, "")
+	if err != nil {
+		return e.BadRequestError("Error saving bid", err)
+	}
+
+	ranked, err = findRankedBids(tx, auction.Id)
+	if err != nil {
+		return e.BadRequestError("Error checking existing bids", err)
+	}
+	winnerIds := []string{}
+	for i, bid := range ranked {
+		tokensToReserve := 0
+		if i < quantity {
+			tokensToReserve = bid.Amount
+			winnerIds = append(winnerIds, bid.UserId)
+		}
+		reserved, err := getReservation(tx, auction.Id, bid.UserId)
+		if err != nil {
+			return e.BadRequestError("Error checking reserved tokens", err)
+		}
+		if err := setReservation(tx, auction.Id, bid.UserId, tokensToReserve); err != nil {
+			return e.BadRequestError("Error updating user tokens", err)
+		}
+		if reserved > 0 && tokensToReserve == 0 {
+			if err := notifyUser(tx, bid.UserId, NotificationEvent{Type: eventOutbid, Auction: auction.Id, Amount: amount, Actor: user.Id}); err != nil {
+				return e.BadRequestError("Failed to create notification", err)
+			}
+		}
+	}
+
+	currentBid := ranked[min(quantity, len(ranked))-1].Amount
+	auction.Set("currentBid", currentBid)
+	auction.Set("winners", winnerIds)
+	applyAntiSnipe(antiSnipeRule(settings, auction), auction, time.Now().UTC())
+	if err := tx.Save(auction); err != nil {
+		return e.BadRequestError("Error updating auction", err)
+	}
+
+	return e.JSON(200, map[string]interface{}{
+		"success":    true,
+		"bid":        bidRecord,
+		"leading":    slices.Contains(winnerIds, user.Id),
+		"currentBid": currentBid,
+	})
+}
+
+// handleSealedBid records a hidden bid on a sealed auction and reserves its full amount.
+// The auction's currentBid and winner stay untouched until the auction is closed.
+func handleSealedBid(e *core.RequestEvent, tx core.App, auction *core.Record, user *core.Record, amount int, maxAmount int, source string) error {
+	if maxAmount > 0 {
+		return e.BadRequestError("Maximum bids are not supported in sealed auctions", nil)
+	}
+	if amount < openingBid(auction.GetInt("startingBid")) {
+		return e.BadRequestError("Bid is too low", nil)
+	}
+	available, err := availableTokens(tx, user, auction.Id)
+	if err != nil {
+		return e.BadRequestError("Error checking reserved tokens", err)
+	}
+	if amount > available {
+		return e.BadRequestError("Insufficient tokens", nil)
+	}
+
+	bidRecord, err := saveBid(tx, auction.Id, user.Id, amount, amount, source, "")
+	if err != nil {
+		return e.BadRequestError("Error saving bid", err)
+	}
+	if err := setReservation(tx, auction.Id, user.Id, amount); err != nil {
+		return e.BadRequestError("Error updating user tokens", err)
+	}
+
+	return e.JSON(200, map[string]interface{}{
+		"success": true,
+		"bid":     bidRecord,
+	})
+}
+
+// handleRetractBid withdraws the current user's bid shortly after it was placed.
+// The previous leaders get their lead and reservations back and the retraction is logged for managers.
+func handleRetractBid(e *core.RequestEvent) error {
+	if e.Auth == nil {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	auctionId := e.Request.PathValue("id")
+	if auctionId == "" {
+		return e.BadRequestError("Auction ID is required", nil)
+	}
+	settings, err := GetSettings(e.App)
+	if err != nil {
+		return e.BadRequestError("Error getting settings", err)
+	}
+	if settings.BidRetractionMinutes <= 0 {
+		return e.BadRequestError("Bid retraction is disabled", nil)
+	}
+
+	var auction, event *core.Record
+	err = e.App.RunInTransaction(func(tx core.App) error {
+		var err error
+		auction, err = tx.FindRecordById("auctions", auctionId)
+		if err != nil {
+			return e.NotFoundError("Auction not found", err)
+		}
+		if auction.GetString("state") != "ongoing" || auction.GetDateTime("endTime").Before(types.NowDateTime()) {
+			return e.BadRequestError("Auction is not active", nil)
+		}
+		bids, err := findRetractableBids(tx, auctionId, e.Auth.Id)
+		if err != nil {
+			return e.BadRequestError("Error finding bid", err)
+		}
+		if len(bids) == 0 {
+			return e.BadRequestError("No bid to retract", nil)
+		}
+		bid := bids[len(bids)-1]
+		timestamp, _ := strconv.ParseInt(bid.GetString("timestamp"), 10, 64)
+		if time.Since(time.Unix(timestamp, 0)) > time.Duration(settings.BidRetractionMinutes)*time.Minute {
+			return e.BadRequestError("Retraction window has passed", nil)
+		}
+
+		wasLeading := auction.GetString("winner") == e.Auth.Id || slices.Contains(auction.GetStringSlice("winners"), e.Auth.Id)
+		if err := retractBid(tx, settings, auction, bids); err != nil {
+			return e.BadRequestError("Error retracting bid", err)
+		}
+		event, err = logAuctionEvent(tx, auctionId, auctionLogBidRetracted, e.Auth.Id, map[string]any{
+			"bid":        bid.Id,
+			"amount":     bid.GetInt("amount"),
+			"maxAmount":  bid.GetInt("maxAmount"),
+			"wasLeading": wasLeading,
+			"currentBid": auction.GetInt("currentBid"),
 		})
+		if err != nil {
+			return e.BadRequestError("Error logging retraction", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := broadcastAuctionEvent(e.App, event); err != nil {
+		e.App.Logger().Error("Error broadcasting auction event", "error", err, "auctionId", auctionId)
+	}
+	return e.JSON(200, map[string]interface{}{
+		"success":    true,
+		"currentBid": auction.GetInt("currentBid"),
 	})
 }
 
@@ -358,6 +1018,65 @@ func seenNotification(e *core.RequestEvent) error {
 	})
 }
 
+// getNotificationPreferences returns the notification preferences of the current user.
+func getNotificationPreferences(e *core.RequestEvent) error {
+	preferences, err := loadNotificationPreferences(e.App, e.Auth.Id)
+	if err != nil {
+		return e.BadRequestError("Error loading notification preferences", err)
+	}
+	return e.JSON(200, preferences)
+}
+
+// setNotificationPreferences stores the notification preferences of the current user.
+func setNotificationPreferences(e *core.RequestEvent) error {
+	var data NotificationPreferences
+	if err := e.BindBody(&data); err != nil {
+		return e.BadRequestError("Invalid data", err)
+	}
+	for event, channels := range data.Events {
+		if !slices.Contains(notificationEvents, event) {
+			return e.BadRequestError("Unknown notification event "+event, nil)
+		}
+		for channel := range channels {
+			if _, err := findDeliveryChannel(channel); err != nil {
+				return e.BadRequestError("Unknown notification channel "+channel, nil)
+			}
+		}
+	}
+	mutedUntil := types.DateTime{}
+	if data.MutedUntil != "" {
+		parsed, err := types.ParseDateTime(data.MutedUntil)
+		if err != nil {
+			return e.BadRequestError("Invalid mutedUntil date", err)
+		}
+		mutedUntil = parsed
+	}
+
+	record, err := findNotificationPreferences(e.App, e.Auth.Id)
+	if err != nil {
+		return e.BadRequestError("Error loading notification preferences", err)
+	}
+	if record == nil {
+		coll, err := e.App.FindCachedCollectionByNameOrId("notificationPreferences")
+		if err != nil {
+			return e.BadRequestError("Error saving notification preferences", err)
+		}
+		record = core.NewRecord(coll)
+		record.Set("user", e.Auth.Id)
+	}
+	record.Set("events", data.Events)
+	record.Set("mutedUntil", mutedUntil)
+	if err := e.App.Save(record); err != nil {
+		return e.BadRequestError("Error saving notification preferences", err)
+	}
+
+	preferences, err := loadNotificationPreferences(e.App, e.Auth.Id)
+	if err != nil {
+		return e.BadRequestError("Error loading notification preferences", err)
+	}
+	return e.JSON(200, preferences)
+}
+
 // clearTokens removes a percentage of tokens from all users.
 func clearTokens(e *core.RequestEvent) error {
 	var data struct {
@@ -388,18 +1107,16 @@ func clearTokens(e *core.RequestEvent) error {
 			currentTokens := userRecord.GetInt("tokens")
 			removedAmountFloat := float64(currentTokens) * (float64(data.Percentage) / 100)
 			removedAmount := int(math.Ceil(removedAmountFloat))
-			userRecord.Set("tokens", currentTokens-removedAmount)
-			if err := tx.Save(userRecord); err != nil {
-				return e.BadRequestError("Error saving user", err)
-			}
-			if err := createTransactionRecord(tx, userRecord.Id, -removedAmount, "Token percentage removal", e.Auth.Id); err != nil {
-				return e.BadRequestError("Failed to create transaction record", err)
+			if err := transferTokens(tx, userRecord.Id, -removedAmount, "Token percentage removal", e.Auth.Id); err != nil {
+				return e.BadRequestError("Error transferring tokens", err)
 			}
 			changeData = append(changeData, ChangeTokens{userRecord.Id, -removedAmount})
 
 		}
 		for _, r := range changeData {
-			notifyUser(r.User, fmt.Sprintf("Your tokens have been updated by %d", r.Amount))
+			if err := notifyUser(tx, r.User, NotificationEvent{Type: eventTokenChange, Amount: r.Amount, Actor: e.Auth.Id}); err != nil {
+				return e.BadRequestError("Failed to create notification", err)
+			}
 		}
 		return e.JSON(200, map[string]interface{}{
 			"success": true,
@@ -521,3 +1238,47 @@ func getDashboardStats(e *core.RequestEvent) error {
 
 	return e.JSON(200, stats)
 }
+
+// repairHealthCheck fixes the users flagged by a token health check.
+// With dryRun the planned changes are only returned, otherwise they are applied and recorded with the approving admin.
+func repairHealthCheck(e *core.RequestEvent) error {
+	if !checkIfUserIsInRole(e.Auth, "admin") {
+		return e.UnauthorizedError("Unauthorized", nil)
+	}
+	var data struct {
+		Mode   string `json:"mode"`
+		DryRun bool   `json:"dryRun"`
+	}
+	if err := e.BindBody(&data); err != nil {
+		return e.BadRequestError("Invalid data", err)
+	}
+	if data.Mode != repairModeTransactions && data.Mode != repairModeBalances {
+		return e.BadRequestError("Mode must be transactions or balances", nil)
+	}
+	healthCheck, err := e.App.FindRecordById("tokenHealthChecks", e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("Health check not found", err)
+	}
+
+	return e.App.RunInTransaction(func(tx core.App) error {
+		changes, err := planHealthCheckRepair(tx, healthCheck, data.Mode)
+		if err != nil {
+			return e.BadRequestError("Error planning repair", err)
+		}
+		if data.DryRun {
+			return e.JSON(200, map[string]interface{}{
+				"dryRun":  true,
+				"changes": changes,
+			})
+		}
+		repair, err := applyHealthCheckRepair(tx, healthCheck, data.Mode, changes, e.Auth.Id)
+		if err != nil {
+			return e.BadRequestError("Error applying repair", err)
+		}
+		return e.JSON(200, map[string]interface{}{
+			"dryRun":  false,
+			"repair":  repair.Id,
+			"changes": changes,
+		})
+	})
+}
@@ -8,6 +8,7 @@ import (
 // RegisterApiRoutes wires API endpoints and middleware for server-side token operations.
 func RegisterApiRoutes(se *core.ServeEvent) {
 	se.Router.POST("/api/app/change-tokens", changeTokensApi).Bind(validateApiTokenMiddleware()).Bind(setUserAuthMiddleware())
+	se.Router.POST("/api/app/bid/{id}", bidApi).Bind(validateApiTokenMiddleware()).Bind(setUserAuthMiddleware())
 	se.Router.GET("/api/version", appVersion)
 
 }
@@ -21,6 +22,11 @@ func changeTokensApi(e *core.RequestEvent) error {
 	return chaneUsersAmount(e)
 }
 
+// bidApi places a bid on behalf of the Discord user of the request.
+func bidApi(e *core.RequestEvent) error {
+	return placeBid(e, bidSourceApi)
+}
+
 // validateApiToken returns the API token record ID for a valid token string.
 func validateApiToken(app core.App, token string) (string, error) {
 	record, err := app.FindFirstRecordByData("apiKeys", "apiKey", token)
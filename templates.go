@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// notificationTemplates renders the English text stored with each notification event.
+// Clients can localize notifications from the event type and fields instead.
+var notificationTemplates = map[string]*template.Template{
+	eventOutbid:             notificationTemplate(eventOutbid, "Your bid was outbid by {{.Amount}} tokens"),
+	eventWon:                notificationTemplate(eventWon, "You won the auction{{with .Data.itemName}} for {{.}}{{end}} with a bid of {{.Amount}} tokens"),
+	eventResultVoided:       notificationTemplate(eventResultVoided, "Your win{{with .Data.itemName}} of {{.}}{{end}} was voided and {{.Amount}} tokens were refunded{{with .Data.reason}}. Reason: {{.}}{{end}}"),
+	eventTokenChange:        notificationTemplate(eventTokenChange, "Your tokens have been updated by {{.Amount}}{{with .Data.reason}}. Reason: {{.}}{{end}}"),
+	eventAuctionOpened:      notificationTemplate(eventAuctionOpened, "Auction for {{.Data.itemName}} is now open for bids"),
+	eventAuctionEnding:      notificationTemplate(eventAuctionEnding, "Auction for {{.Data.itemName}} ends in less than {{.Data.minutes}} minutes"),
+	eventAuctionEnded:       notificationTemplate(eventAuctionEnded, "Auction{{with .Data.itemName}} for {{.}}{{end}} has ended"),
+	eventAuctionCancelled:   notificationTemplate(eventAuctionCancelled, "Auction for {{.Data.itemName}} was cancelled and your tokens were released{{with .Data.reason}}. Reason: {{.}}{{end}}"),
+	eventAuctionUnsold:      notificationTemplate(eventAuctionUnsold, "Auction for {{.Data.itemName}} closed below its reserve price and was not sold"),
+	eventHealthCheckFailure: notificationTemplate(eventHealthCheckFailure, "Token health check failed"),
+	eventItemsUpdated:       notificationTemplate(eventItemsUpdated, "Items have been updated from TLDB"),
+}
+
+func notificationTemplate(name string, text string) *template.Template {
+	return template.Must(template.New(name).Option("missingkey=zero").Parse(text))
+}
+
+// renderNotification renders the text of a notification event from its template.
+func renderNotification(event NotificationEvent) (string, error) {
+	tmpl, ok := notificationTemplates[event.Type]
+	if !ok {
+		return "", fmt.Errorf("unknown notification event %q", event.Type)
+	}
+	var text strings.Builder
+	if err := tmpl.Execute(&text, event); err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// auctionTimers holds a close timer for every ongoing auction so it closes right at its end time.
+// The finishAuctions cron stays as a safety net for anything a timer missed.
+var auctionTimers = struct {
+	sync.Mutex
+	app    core.App
+	timers map[string]*time.Timer
+}{timers: map[string]*time.Timer{}}
+
+// startAuctionTimers arms a close timer for every ongoing auction.
+func startAuctionTimers(app core.App) error {
+	auctionTimers.Lock()
+	auctionTimers.app = app
+	auctionTimers.Unlock()
+
+	records, err := app.FindRecordsByFilter("auctions", "state = 'ongoing'", "", 0, 0, nil)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		syncAuctionTimer(record)
+	}
+	return nil
+}
+
+// stopAuctionTimers disarms every timer, auctions are left to the cron until the timers are started again.
+func stopAuctionTimers() {
+	auctionTimers.Lock()
+	defer auctionTimers.Unlock()
+	for auctionId, timer := range auctionTimers.timers {
+		timer.Stop()
+		delete(auctionTimers.timers, auctionId)
+	}
+	auctionTimers.app = nil
+}
+
+// syncAuctionTimer arms the close timer of an ongoing auction at its current end time and disarms it otherwise.
+func syncAuctionTimer(auction *core.Record) {
+	if auction.GetString("state") != "ongoing" {
+		cancelAuctionClose(auction.Id)
+		return
+	}
+	scheduleAuctionClose(auction.Id, auction.GetDateTime("endTime").Time())
+}
+
+// scheduleAuctionClose arms a timer closing an auction at the end time, replacing an earlier timer.
+func scheduleAuctionClose(auctionId string, endTime time.Time) {
+	auctionTimers.Lock()
+	defer auctionTimers.Unlock()
+	if auctionTimers.app == nil {
+		return
+	}
+	if timer, ok := auctionTimers.timers[auctionId]; ok {
+		timer.Stop()
+	}
+	app := auctionTimers.app
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(endTime), func() {
+		auctionTimers.Lock()
+		if auctionTimers.timers[auctionId] == timer {
+			delete(auctionTimers.timers, auctionId)
+		}
+		auctionTimers.Unlock()
+		if _, err := closeDueAuction(app, auctionId); err != nil {
+			app.Logger().Error("Error closing auction", "error", err, "auctionId", auctionId)
+		}
+	})
+	auctionTimers.timers[auctionId] = timer
+}
+
+// cancelAuctionClose disarms the close timer of an auction.
+func cancelAuctionClose(auctionId string) {
+	auctionTimers.Lock()
+	defer auctionTimers.Unlock()
+	if timer, ok := auctionTimers.timers[auctionId]; ok {
+		timer.Stop()
+		delete(auctionTimers.timers, auctionId)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAuctionTimerClosesAuction verifies an armed timer closes the auction right at its end time.
+func TestAuctionTimerClosesAuction(t *testing.T) {
+	app := newTestApp(t)
+	if err := startAuctionTimers(app.App); err != nil {
+		t.Fatalf("startAuctionTimers returned error: %v", err)
+	}
+	t.Cleanup(stopAuctionTimers)
+
+	auction := createTestAuction(t, app, 10)
+	auction.Set("endTime", time.Now().UTC().Add(200*time.Millisecond))
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+	syncAuctionTimer(auction)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		record, err := app.FindRecordById("auctions", auction.Id)
+		if err != nil {
+			t.Fatalf("failed to reload auction: %v", err)
+		}
+		if record.GetString("state") == "finished" {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("expected the timer to finish the auction")
+}
+
+// TestCloseDueAuctionReschedulesExtendedAuction ensures a timer firing before an extended end time rearms itself.
+func TestCloseDueAuctionReschedulesExtendedAuction(t *testing.T) {
+	app := newTestApp(t)
+	if err := startAuctionTimers(app.App); err != nil {
+		t.Fatalf("startAuctionTimers returned error: %v", err)
+	}
+	t.Cleanup(stopAuctionTimers)
+
+	auction := createTestAuction(t, app, 10)
+	if _, err := closeDueAuction(app.App, auction.Id); err != nil {
+		t.Fatalf("closeDueAuction returned error: %v", err)
+	}
+
+	record, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	if record.GetString("state") != "ongoing" {
+		t.Fatalf("expected auction to stay ongoing, got %q", record.GetString("state"))
+	}
+	auctionTimers.Lock()
+	_, ok := auctionTimers.timers[auction.Id]
+	auctionTimers.Unlock()
+	if !ok {
+		t.Fatalf("expected the auction close to be rescheduled")
+	}
+}
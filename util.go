@@ -14,11 +14,23 @@ func checkIfUserIsInRole(record *core.Record, role string) bool {
 	return slices.Contains(record.GetStringSlice("role"), role)
 }
 
+// isManagerClient reports whether a realtime client is signed in as a manager.
+func isManagerClient(auth *core.Record) bool {
+	return checkIfUserIsInRole(auth, "manager")
+}
+
+// isMemberClient reports whether a realtime client may follow auctions without being a manager,
+// which like the auctions list rule requires a validated user.
+func isMemberClient(auth *core.Record) bool {
+	return auth.GetBool("validated") && !isManagerClient(auth)
+}
+
 // createTransactionRecord stores a transaction record for the given user and author.
-func createTransactionRecord(app core.App, userId string, amount int, note string, authorId string) error {
+// Token balances change through transferTokens, which lists the movement here.
+func createTransactionRecord(app core.App, userId string, amount int, note string, authorId string) (*core.Record, error) {
 	coll, err := app.FindCachedCollectionByNameOrId("transactions")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	record := core.NewRecord(coll)
 	record.Set("user", userId)
@@ -26,9 +38,9 @@ func createTransactionRecord(app core.App, userId string, amount int, note strin
 	record.Set("note", note)
 	record.Set("author", authorId)
 	if err := app.Save(record); err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return record, nil
 }
 
 // GetSettings returns the singleton settings record, inserting a default when missing.
@@ -28,7 +28,7 @@ func TestCreateTransactionRecord(t *testing.T) {
 	user := createTestUser(t, app, "player@example.com", []string{"member"})
 	author := createTestUser(t, app, "author@example.com", []string{"admin"})
 
-	if err := createTransactionRecord(app.App, user.Id, 25, "loot transfer", author.Id); err != nil {
+	if _, err := createTransactionRecord(app.App, user.Id, 25, "loot transfer", author.Id); err != nil {
 		t.Fatalf("createTransactionRecord returned error: %v", err)
 	}
 
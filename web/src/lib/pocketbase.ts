@@ -10,6 +10,23 @@ export async function subscribeToAuctionUpdate(recordId: string, callback: (reco
   });
 }
 
+export type AuctionUpdate = {
+  auction: string;
+  state: string;
+  currentBid: number;
+  winner: string;
+  winners: string[] | null;
+  winnerNames: string[] | null;
+  endTime: string;
+};
+
+export async function subscribeToAuctionStream(recordId: string, callback: (update: AuctionUpdate) => void) {
+  return pb.realtime.subscribe(`auction/${recordId}`, (update: AuctionUpdate) => {
+    console.log('Auction stream update:', update);
+    callback(update);
+  });
+}
+
 export async function unsubscribeFromAuctionUpdates() {
   pb.collection('auctions').unsubscribe();
 }
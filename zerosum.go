@@ -0,0 +1,128 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	zeroSumRemainderPool   = "pool"
+	zeroSumRemainderSpread = "spread"
+)
+
+const zeroSumShareNote = "Zero-sum share of auction win"
+
+// zeroSumRecipients returns the members who share a win: the configured attendees or all validated users, without the winners.
+// They are ordered by balance, poorest first, which is the order spread remainders are handed out in.
+func zeroSumRecipients(app core.App, settings *Settings, winnerIds []string) ([]*core.Record, error) {
+	var users []*core.Record
+	var err error
+	if len(settings.ZeroSumAttendees) > 0 {
+		users, err = app.FindRecordsByIds("users", settings.ZeroSumAttendees)
+	} else {
+		users, err = app.FindAllRecords("users", dbx.HashExp{"validated": true})
+	}
+	if err != nil {
+		return nil, err
+	}
+	users = slices.DeleteFunc(users, func(user *core.Record) bool {
+		return slices.Contains(winnerIds, user.Id)
+	})
+	slices.SortFunc(users, func(a, b *core.Record) int {
+		return cmp.Or(cmp.Compare(a.GetInt("tokens"), b.GetInt("tokens")), cmp.Compare(a.Id, b.Id))
+	})
+	return users, nil
+}
+
+// zeroSumShares splits an amount equally between recipients. The remainder stays in the guild pool
+// unless it is spread one token each over the first recipients.
+func zeroSumShares(amount int, recipientIds []string, remainder string) map[string]int {
+	shares := map[string]int{}
+	if len(recipientIds) == 0 || amount <= 0 {
+		return shares
+	}
+	share := amount / len(recipientIds)
+	left := amount % len(recipientIds)
+	for i, userId := range recipientIds {
+		credit := share
+		if remainder == zeroSumRemainderSpread && i < left {
+			credit++
+		}
+		if credit > 0 {
+			shares[userId] = credit
+		}
+	}
+	return shares
+}
+
+// distributeZeroSum credits the price of a win to the members who won nothing in the auction when zero-sum
+// distribution is enabled and returns the credited shares per user.
+func distributeZeroSum(app core.App, auctionId string, winnerIds []string, amount int) (map[string]int, error) {
+	settings, err := GetSettings(app)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.ZeroSumDistribution {
+		return nil, nil
+	}
+	recipients, err := zeroSumRecipients(app, settings, winnerIds)
+	if err != nil {
+		return nil, err
+	}
+	recipientIds := make([]string, len(recipients))
+	for i, recipient := range recipients {
+		recipientIds[i] = recipient.Id
+	}
+	shares := zeroSumShares(amount, recipientIds, settings.ZeroSumRemainder)
+	for _, userId := range recipientIds {
+		if shares[userId] == 0 {
+			continue
+		}
+		if err := transferTokens(app, userId, shares[userId], zeroSumShareNote, ""); err != nil {
+			return nil, err
+		}
+		if err := notifyUser(app, userId, NotificationEvent{
+			Type:    eventTokenChange,
+			Auction: auctionId,
+			Amount:  shares[userId],
+			Data:    map[string]any{"reason": zeroSumShareNote},
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return shares, nil
+}
+
+// reverseZeroSum takes back the shares a voided result distributed.
+func reverseZeroSum(app core.App, result *core.Record, reason string, voidedBy string) error {
+	shares := map[string]int{}
+	if result.GetString("distribution") != "" {
+		if err := result.UnmarshalJSONField("distribution", &shares); err != nil {
+			return err
+		}
+	}
+	userIds := make([]string, 0, len(shares))
+	for userId := range shares {
+		userIds = append(userIds, userId)
+	}
+	slices.Sort(userIds)
+	note := "Reversal of zero-sum share for voided auction win. Reason: " + reason
+	for _, userId := range userIds {
+		if err := transferTokens(app, userId, -shares[userId], note, voidedBy); err != nil {
+			return err
+		}
+		if err := notifyUser(app, userId, NotificationEvent{
+			Type:    eventTokenChange,
+			Auction: result.GetString("auction"),
+			Amount:  -shares[userId],
+			Actor:   voidedBy,
+			Data:    map[string]any{"reason": note},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// TestZeroSumShares verifies equal shares with the remainder kept in the pool or spread over the first recipients.
+func TestZeroSumShares(t *testing.T) {
+	recipients := []string{"a", "b", "c"}
+	if got := zeroSumShares(20, recipients, zeroSumRemainderPool); !maps.Equal(got, map[string]int{"a": 6, "b": 6, "c": 6}) {
+		t.Fatalf("unexpected pool shares %v", got)
+	}
+	if got := zeroSumShares(20, recipients, zeroSumRemainderSpread); !maps.Equal(got, map[string]int{"a": 7, "b": 7, "c": 6}) {
+		t.Fatalf("unexpected spread shares %v", got)
+	}
+	if got := zeroSumShares(2, recipients, zeroSumRemainderPool); len(got) != 0 {
+		t.Fatalf("expected no shares below one token each, got %v", got)
+	}
+	if got := zeroSumShares(20, nil, zeroSumRemainderSpread); len(got) != 0 {
+		t.Fatalf("expected no shares without recipients, got %v", got)
+	}
+}
+
+// TestFinishAuctionDistributesZeroSum verifies a win is shared among the validated members and reversed when voided.
+func TestFinishAuctionDistributesZeroSum(t *testing.T) {
+	app := newTestApp(t)
+
+	enableTestZeroSum(t, app, zeroSumRemainderSpread, nil)
+	winner := createTestUser(t, app, "winner@example.com", []string{"member"})
+	poor := createTestUser(t, app, "poor@example.com", []string{"member"})
+	rich := createTestUser(t, app, "rich@example.com", []string{"member"})
+	absent := createTestUser(t, app, "absent@example.com", []string{"member"})
+	for _, user := range []*core.Record{winner, poor, rich} {
+		user.Set("validated", true)
+		if err := app.Save(user); err != nil {
+			t.Fatalf("failed to validate user: %v", err)
+		}
+	}
+	setTestTokens(t, app, winner, 100)
+	setTestTokens(t, app, poor, 10)
+	setTestTokens(t, app, rich, 50)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, winner, auction.Id, `{"amount":25}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	expireTestAuction(t, app, auction)
+
+	if err := finishAuction(app); err != nil {
+		t.Fatalf("finishAuction returned error: %v", err)
+	}
+	assertTokens(t, app, winner.Id, 75)
+	assertTokens(t, app, poor.Id, 23)
+	assertTokens(t, app, rich.Id, 62)
+	assertTokens(t, app, absent.Id, 0)
+
+	result, err := app.FindFirstRecordByFilter("auctionsResult", "auction = {:auctionId}", dbx.Params{"auctionId": auction.Id})
+	if err != nil {
+		t.Fatalf("failed to find result: %v", err)
+	}
+	if err := voidAuctionResult(app, result, "Wrong item", ""); err != nil {
+		t.Fatalf("voidAuctionResult returned error: %v", err)
+	}
+	assertTokens(t, app, winner.Id, 100)
+	assertTokens(t, app, poor.Id, 10)
+	assertTokens(t, app, rich.Id, 50)
+}
+
+// TestFinishAuctionDistributesZeroSumToAttendees verifies a configured attendee list replaces the validated users.
+func TestFinishAuctionDistributesZeroSumToAttendees(t *testing.T) {
+	app := newTestApp(t)
+
+	winner := createTestUser(t, app, "winner@example.com", []string{"member"})
+	attendee := createTestUser(t, app, "attendee@example.com", []string{"member"})
+	other := createTestUser(t, app, "other@example.com", []string{"member"})
+	other.Set("validated", true)
+	if err := app.Save(other); err != nil {
+		t.Fatalf("failed to validate user: %v", err)
+	}
+	enableTestZeroSum(t, app, zeroSumRemainderPool, []string{winner.Id, attendee.Id})
+	setTestTokens(t, app, winner, 100)
+	auction := createTestAuction(t, app, 10)
+	placeTestBid(t, app, winner, auction.Id, `{"amount":25}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	expireTestAuction(t, app, auction)
+
+	if err := finishAuction(app); err != nil {
+		t.Fatalf("finishAuction returned error: %v", err)
+	}
+	assertTokens(t, app, winner.Id, 75)
+	assertTokens(t, app, attendee.Id, 25)
+	assertTokens(t, app, other.Id, 0)
+}
+
+// TestFinishAuctionDistributesZeroSumWithoutWinners verifies no winner of a multi-unit auction gets a share of another unit.
+func TestFinishAuctionDistributesZeroSumWithoutWinners(t *testing.T) {
+	app := newTestApp(t)
+
+	enableTestZeroSum(t, app, zeroSumRemainderPool, nil)
+	first := createTestUser(t, app, "first@example.com", []string{"member"})
+	second := createTestUser(t, app, "second@example.com", []string{"member"})
+	loser := createTestUser(t, app, "loser@example.com", []string{"member"})
+	bystander := createTestUser(t, app, "bystander@example.com", []string{"member"})
+	for _, user := range []*core.Record{first, second, loser, bystander} {
+		user.Set("validated", true)
+		if err := app.Save(user); err != nil {
+			t.Fatalf("failed to validate user: %v", err)
+		}
+	}
+	for _, user := range []*core.Record{first, second, loser} {
+		setTestTokens(t, app, user, 100)
+	}
+	auction := createTestAuction(t, app, 10)
+	auction.Set("quantity", 2)
+	if err := app.Save(auction); err != nil {
+		t.Fatalf("failed to save auction: %v", err)
+	}
+	placeTestBid(t, app, loser, auction.Id, `{"amount":20}`)
+	placeTestBid(t, app, first, auction.Id, `{"amount":50}`)
+	placeTestBid(t, app, second, auction.Id, `{"amount":30}`)
+	auction, err := app.FindRecordById("auctions", auction.Id)
+	if err != nil {
+		t.Fatalf("failed to reload auction: %v", err)
+	}
+	expireTestAuction(t, app, auction)
+
+	if err := finishAuction(app); err != nil {
+		t.Fatalf("finishAuction returned error: %v", err)
+	}
+	assertTokens(t, app, first.Id, 50)
+	assertTokens(t, app, second.Id, 70)
+	assertTokens(t, app, loser.Id, 140)
+	assertTokens(t, app, bystander.Id, 40)
+}
+
+// enableTestZeroSum turns on zero-sum distribution with the given remainder handling and attendees.
+func enableTestZeroSum(t *testing.T, app *pocketbase.PocketBase, remainder string, attendees []string) {
+	t.Helper()
+
+	insertSettingsRecord(t, app)
+	record, err := app.FindFirstRecordByFilter("settings", "")
+	if err != nil {
+		t.Fatalf("failed to find settings: %v", err)
+	}
+	record.Set("zeroSumDistribution", true)
+	record.Set("zeroSumRemainder", remainder)
+	record.Set("zeroSumAttendees", attendees)
+	if err := app.Save(record); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+}